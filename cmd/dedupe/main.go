@@ -0,0 +1,49 @@
+// One-off cleanup of pages which are stored under more than one spelling
+// ("Foo_bar", "foo bar", "Foo_bar#History"). Renames them to their canonical
+// name.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/alicebob/verssion/core"
+)
+
+var (
+	dbURL  = flag.String("db", "postgresql:///verssion", "postgres URL")
+	dryRun = flag.Bool("n", false, "only print what would be merged")
+)
+
+func main() {
+	flag.Parse()
+
+	db, err := core.NewPostgres(*dbURL, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pg: %s\n", err)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	pages, err := db.Known(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "known: %s\n", err)
+		os.Exit(1)
+	}
+	for _, p := range pages {
+		c := core.Canonical(p)
+		if c == p {
+			continue
+		}
+		fmt.Printf("%q -> %q\n", p, c)
+		if *dryRun {
+			continue
+		}
+		if err := db.MergePage(ctx, p, c); err != nil {
+			fmt.Fprintf(os.Stderr, "merge %q: %s\n", p, err)
+			os.Exit(1)
+		}
+	}
+}
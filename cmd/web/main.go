@@ -1,10 +1,13 @@
 package main
 
 import (
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 
 	"github.com/alicebob/verssion/core"
@@ -12,10 +15,21 @@ import (
 )
 
 var (
-	baseURL = flag.String("base", "http://localhost:3141", "base URL")
-	dbURL   = flag.String("db", "postgresql:///verssion", "postgres URL")
-	listen  = flag.String("listen", ":3141", "http listen")
-	static  = flag.String("static", "", "subdir with static files")
+	baseURL        = flag.String("base", "http://localhost:3141", "base URL")
+	dbURL          = flag.String("db", "postgresql:///verssion", "database URL: 'postgresql://...', 'sqlite:<file>', or 'file:<file>'")
+	dbConns        = flag.Int("dbconns", core.DefaultConns, "max postgres connections")
+	listen         = flag.String("listen", ":3141", "http listen")
+	static         = flag.String("static", "", "subdir with static files")
+	source         = flag.String("source", "html", "where to read wikipedia versions: 'html', 'wikitext', or 'wikidata'")
+	rules          = flag.String("rules", "", "JSON file with infobox rules for the 'html' source. Uses the built-in rules if empty")
+	timeout        = flag.Duration("timeout", core.DefaultTimeout, "spider request timeout")
+	maxSize        = flag.Int64("maxsize", core.DefaultMaxSize, "max spider response size, in bytes")
+	proxy          = flag.String("proxy", "", "spider HTTP proxy URL. Uses $HTTPS_PROXY if empty")
+	caCert         = flag.String("cacert", "", "PEM file with extra CA certificates for the spider")
+	confirmFetches = flag.Int("confirmfetches", 0, "a changed version needs to be seen this many fetches in a row before it's in the feeds. Every fetch after the first delays it by 6h or more. 0 to disable")
+	confirmAge     = flag.Duration("confirmage", 0, "or a changed version needs to be seen for this long. 0 to disable")
+	adminPassword  = flag.String("adminpassword", "", "password for /admin/ (user 'admin'). Disabled if empty")
+	migrate        = flag.Bool("migrate", false, "only update the database schema, and exit")
 )
 
 func main() {
@@ -26,13 +40,78 @@ func main() {
 		os.Exit(2)
 	}
 
-	db, err := core.NewPostgres(*dbURL)
+	store, err := core.Open(*dbURL, *dbConns)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "pg: %s\n", err)
+		fmt.Fprintf(os.Stderr, "db: %s\n", err)
 		os.Exit(2)
 	}
+	from, to, err := store.Migrate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		os.Exit(2)
+	}
+	if from != to {
+		fmt.Printf("migrated database schema from version %d to %d\n", from, to)
+	}
+	if *migrate {
+		return
+	}
+	db := core.NewConfirming(store, core.ConfirmPolicy{
+		Fetches: *confirmFetches,
+		Age:     *confirmAge,
+	})
 
-	mux := web.Mux(*baseURL, db, web.WikiFetcher(), *static)
+	hc := core.HTTPConfig{
+		Timeout: *timeout,
+		MaxSize: *maxSize,
+	}
+	if *proxy != "" {
+		if hc.Proxy, err = url.Parse(*proxy); err != nil {
+			fmt.Fprintf(os.Stderr, "proxy: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	if *caCert != "" {
+		pem, err := ioutil.ReadFile(*caCert)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cacert: %s\n", err)
+			os.Exit(2)
+		}
+		if hc.RootCAs, err = x509.SystemCertPool(); err != nil {
+			hc.RootCAs = x509.NewCertPool()
+		}
+		if !hc.RootCAs.AppendCertsFromPEM(pem) {
+			fmt.Fprintf(os.Stderr, "cacert: no certificates found in %s\n", *caCert)
+			os.Exit(2)
+		}
+	}
+	core.ConfigureHTTP(hc)
+
+	var wiki core.Source
+	switch *source {
+	case "html":
+		w := core.Wikipedia{}
+		if *rules != "" {
+			if w.Rules, err = core.LoadRules(*rules); err != nil {
+				fmt.Fprintf(os.Stderr, "rules: %s\n", err)
+				os.Exit(2)
+			}
+		}
+		wiki = w
+	case "wikitext":
+		wiki = core.Wikitext{API: core.WikiAPI}
+	case "wikidata":
+		wiki = core.Wikidata{API: core.WikidataAPI}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown source: %q\n", *source)
+		os.Exit(2)
+	}
+	fetch := web.SourceFetcher(core.DefaultSources(wiki))
+
+	mux := web.Mux(*baseURL, db, fetch, *static)
+	if *adminPassword != "" {
+		web.Admin(mux, *baseURL, db, *adminPassword)
+	}
 
 	fmt.Printf("listening on %s...\n", *listen)
 	log.Fatal(http.ListenAndServe(*listen, mux))
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Canonical normalizes a page name, so different spellings of the same
+// wikipedia page end up as the same key: "postgreSQL", "Postgre SQL", and
+// "Postgre%53QL" all become "PostgreSQL". Spaces become underscores, and the
+// first letter is uppercased, like wikipedia does. A section
+// ("PostgreSQL#History") is dropped. An infobox selector
+// ("PostgreSQL|PostgreSQL License") is kept, with underscores. Pages in a
+// namespace ("pypi:django") lose any selector.
+func Canonical(page string) string {
+	if u, err := url.PathUnescape(page); err == nil {
+		page = u
+	}
+	if i := strings.Index(page, "#"); i >= 0 {
+		page = page[:i]
+	}
+	page, caption := Selector(page)
+	page = strings.TrimSpace(page)
+	if ns, id := Namespace(page); ns != "" {
+		return ns + ":" + strings.TrimSpace(id)
+	}
+	lang, title := Language(page)
+	title = underscores(title)
+	if r, n := utf8.DecodeRuneInString(title); n > 0 {
+		title = string(unicode.ToUpper(r)) + title[n:]
+	}
+	return WithSelector(LangPage(lang, title), underscores(caption))
+}
+
+// Selector splits "Page|caption" into the page and the caption of the infobox
+// to use. The caption is empty if there is no selector. Wikipedia titles can't
+// have a "|", so it doesn't clash with a "#section".
+func Selector(page string) (string, string) {
+	if i := strings.Index(page, "|"); i >= 0 {
+		return page[:i], page[i+1:]
+	}
+	return page, ""
+}
+
+// WithSelector is the inverse of Selector
+func WithSelector(page, caption string) string {
+	if caption == "" {
+		return page
+	}
+	return page + "|" + caption
+}
+
+// sameCaption compares infobox captions. Case, spaces, and underscores don't
+// matter.
+func sameCaption(a, b string) bool {
+	return strings.EqualFold(underscores(a), underscores(b))
+}
+
+// underscores replaces all runs of spaces and underscores with a single
+// underscore, and trims them.
+func underscores(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || unicode.IsSpace(r)
+	}), "_")
+}
+
+var canonicalRe = regexp.MustCompile(`<link rel="canonical" href="[^"]*/wiki/([^"]+)"`)
+
+// canonicalLink finds the canonical page name in a wikipedia HTML page.
+// Wikipedia serves redirect pages, and spelling variations, with a 200 and
+// the content of the actual page. Empty if there is no such link.
+func canonicalLink(body []byte, lang string) string {
+	m := canonicalRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return Canonical(LangPage(lang, html.UnescapeString(string(m[1]))))
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	for page, want := range map[string]string{
+		"PostgreSQL":                    "PostgreSQL",
+		"postgreSQL":                    "PostgreSQL",
+		"PostgreSQL#History":            "PostgreSQL",
+		"PostgreSQL|PostgreSQL License": "PostgreSQL|PostgreSQL_License",
+		"postgreSQL| server ":           "PostgreSQL|server",
+		"PostgreSQL|":                   "PostgreSQL",
+		"PostgreSQL|Server#History":     "PostgreSQL|Server",
+		"Postgre%53QL":                  "PostgreSQL",
+		"Foo bar":                       "Foo_bar",
+		"Foo__bar_":                     "Foo_bar",
+		" Foo_bar ":                     "Foo_bar",
+		"Python_(programming_language)": "Python_(programming_language)",
+		"C%2B%2B":                       "C++",
+		"émacs":                         "Émacs",
+		"de:postgreSQL":                 "de:PostgreSQL",
+		"en:PostgreSQL":                 "PostgreSQL",
+		"pypi:django#files":             "pypi:django",
+		"npm:@angular/core":             "npm:@angular/core",
+	} {
+		if have := Canonical(page); have != want {
+			t.Errorf("%q: have %q, want %q", page, have, want)
+		}
+	}
+}
+
+func TestGetPageCanonical(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./data/python.html")
+	}))
+	defer s.Close()
+
+	// wikipedia serves redirect pages with the content of the target page
+	_, err := GetPage(ctx, "Python", s.URL)
+	if have, want := err, (ErrRedirect{Page: "Python", To: "Python_(programming_language)"}); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	p, err := GetPage(ctx, "Python_(programming_language)", s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.Page, "Python_(programming_language)"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
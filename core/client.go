@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the deadline for a single spider request, including
+	// reading the body.
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxSize is the largest response body we read. Big wikipedia
+	// articles are around 1MB.
+	DefaultMaxSize = 10 << 20
+)
+
+// ErrTooLarge is returned when a response is larger than the max size
+var ErrTooLarge = errors.New("response too large")
+
+// HTTPConfig configures the HTTP client which is used for all spidering.
+type HTTPConfig struct {
+	Timeout time.Duration // DefaultTimeout if 0
+	MaxSize int64         // DefaultMaxSize if 0
+	Proxy   *url.URL      // uses $HTTPS_PROXY &c. if nil
+	RootCAs *x509.CertPool
+	// Transport replaces the default transport, and ignores Proxy and
+	// RootCAs. Tests can use this to send requests to an httptest server.
+	Transport http.RoundTripper
+}
+
+type spider struct {
+	client  *http.Client
+	maxSize int64
+}
+
+var (
+	spiderMu sync.Mutex
+	current  = newSpider(HTTPConfig{})
+)
+
+// ConfigureHTTP replaces the spider's HTTP client
+func ConfigureHTTP(c HTTPConfig) {
+	s := newSpider(c)
+	spiderMu.Lock()
+	defer spiderMu.Unlock()
+	current = s
+}
+
+func newSpider(c HTTPConfig) spider {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = DefaultMaxSize
+	}
+	t := c.Transport
+	if t == nil {
+		// the settings of http.DefaultTransport
+		tr := &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		}
+		if c.Proxy != nil {
+			tr.Proxy = http.ProxyURL(c.Proxy)
+		}
+		if c.RootCAs != nil {
+			tr.TLSClientConfig = &tls.Config{RootCAs: c.RootCAs}
+		}
+		t = tr
+	}
+	return spider{
+		client: &http.Client{
+			Transport: t,
+			Timeout:   c.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxSize: c.MaxSize,
+	}
+}
+
+// do runs a request with the configured client. The body of the response
+// gives ErrTooLarge after the max size.
+func do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	spiderMu.Lock()
+	s := current
+	spiderMu.Unlock()
+
+	r, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = &limitedBody{ReadCloser: r.Body, left: s.maxSize}
+	return r, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// is there more?
+		var b [1]byte
+		if n, _ := l.ReadCloser.Read(b[:]); n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// toServer sends all requests to an httptest server
+type toServer string
+
+func (s toServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, err := url.Parse(string(s))
+	if err != nil {
+		return nil, err
+	}
+	r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestConfigureHTTP(t *testing.T) {
+	ctx := context.Background()
+	defer ConfigureHTTP(HTTPConfig{})
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wiki/Git":
+			http.ServeFile(w, r, "./data/git.html")
+		case "/wiki/Slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer s.Close()
+
+	ConfigureHTTP(HTTPConfig{Transport: toServer(s.URL)})
+	p, err := GetPage(ctx, "Git", WikiURL("Git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.StableVersion, "2.14.2 / 22 September 2017"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	ConfigureHTTP(HTTPConfig{Transport: toServer(s.URL), MaxSize: 1000})
+	if _, err := GetPage(ctx, "Git", WikiURL("Git")); err != ErrTooLarge {
+		t.Errorf("have %v, want %v", err, ErrTooLarge)
+	}
+
+	ConfigureHTTP(HTTPConfig{Transport: toServer(s.URL), Timeout: 50 * time.Millisecond})
+	_, err = GetPage(ctx, "Slow", WikiURL("Slow"))
+	if err == nil || !strings.Contains(err.Error(), "Client.Timeout") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
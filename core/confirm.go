@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConfirmPolicy decides when a changed version is real. Wikipedia infoboxes
+// get vandalized, and reverted, so by default a change has to be seen on a
+// few fetches in a row, or for a while, before it shows up in the feeds.
+// The zero policy confirms everything right away.
+type ConfirmPolicy struct {
+	Fetches int           // seen on this many fetches in a row, or
+	Age     time.Duration // seen for at least this long
+}
+
+// confirmed is true if the pending change is confirmed at t
+func (c ConfirmPolicy) confirmed(p Pending, t time.Time) bool {
+	if c.Fetches <= 0 && c.Age <= 0 {
+		return true
+	}
+	return (c.Fetches > 0 && p.Seen >= c.Fetches) ||
+		(c.Age > 0 && t.Sub(p.Since) >= c.Age)
+}
+
+// Pending is a changed page which isn't confirmed yet
+type Pending struct {
+	Page  Page      // the new version, from the latest fetch
+	Since time.Time // first seen
+	Seen  int       // fetches in a row with this version
+}
+
+// Confirming is a DB which only stores changed pages after the policy
+// confirms them. Until then they're in Pending. Changes which are reverted
+// before they are confirmed are dropped.
+type Confirming struct {
+	DB
+	Policy ConfirmPolicy
+	mu     sync.Mutex
+	pages  map[string]*sync.Mutex // serializes Store per page
+}
+
+// NewConfirming wraps a DB with a policy
+func NewConfirming(db DB, p ConfirmPolicy) *Confirming {
+	return &Confirming{
+		DB:     db,
+		Policy: p,
+	}
+}
+
+func (c *Confirming) lock(page string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pages == nil {
+		c.pages = map[string]*sync.Mutex{}
+	}
+	l, ok := c.pages[page]
+	if !ok {
+		l = &sync.Mutex{}
+		c.pages[page] = l
+	}
+	return l
+}
+
+// Store stores the page if it's new, unchanged, or a confirmed change. An
+// unconfirmed change stores the previous version again, with the new
+// timestamp. The same fetch result stored twice, such as from a cache, only
+// counts once.
+func (c *Confirming) Store(ctx context.Context, p Page) error {
+	l := c.lock(p.Page)
+	l.Lock()
+	defer l.Unlock()
+
+	last, err := c.DB.Last(ctx, p.Page)
+	if err != nil {
+		if _, ok := err.(ErrNotFound); !ok {
+			return err
+		}
+		last = nil
+	}
+	if last == nil || !changed(*last, p) {
+		// nothing to confirm. Anything pending was reverted.
+		if err := c.DB.DeletePending(ctx, p.Page); err != nil {
+			return err
+		}
+		return c.DB.Store(ctx, p)
+	}
+
+	pend, err := c.DB.LoadPending(ctx, p.Page)
+	if err != nil {
+		return err
+	}
+	if pend != nil && pend.Page.T.Equal(p.T) && pend.Page.Revision == p.Revision && !changed(pend.Page, p) {
+		// already counted
+		return nil
+	}
+	if pend == nil || changed(pend.Page, p) {
+		pend = &Pending{Since: p.T}
+	}
+	pend.Page = p
+	pend.Seen++
+	if c.Policy.confirmed(*pend, p.T) {
+		if err := c.DB.DeletePending(ctx, p.Page); err != nil {
+			return err
+		}
+		return c.DB.Store(ctx, p)
+	}
+	if err := c.DB.SetPending(ctx, *pend); err != nil {
+		return err
+	}
+	// Keep the confirmed version, with its ETag and revision, so the next
+	// fetch parses the page again.
+	keep := *last
+	keep.T = p.T
+	return c.DB.Store(ctx, keep)
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfirmingFetches(t *testing.T) {
+	ctx := context.Background()
+	var (
+		now = time.Now().UTC()
+		db  = NewConfirming(NewMemory(), ConfirmPolicy{Fetches: 3})
+	)
+	fetch := func(min int, version string, revision int64) {
+		t.Helper()
+		p := Page{
+			Page:          "Foo",
+			T:             now.Add(time.Duration(min) * time.Minute),
+			StableVersion: version,
+			Revision:      revision,
+		}
+		if err := db.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stable := func(want string) {
+		t.Helper()
+		l, err := db.Last(ctx, "Foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := l.StableVersion; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+	pending := func(want int) {
+		t.Helper()
+		ps, err := db.PendingAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := len(ps); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	// new pages are fine
+	fetch(0, "1.0", 1)
+	stable("1.0")
+	pending(0)
+
+	// vandalism, reverted
+	fetch(1, "6.6.6", 2)
+	stable("1.0")
+	pending(1)
+	fetch(2, "6.6.6", 2)
+	stable("1.0")
+	fetch(3, "1.0", 3)
+	stable("1.0")
+	pending(0)
+
+	// the previous revision is kept, so the page gets parsed again
+	fetch(4, "2.0", 4)
+	if l, _ := db.Last(ctx, "Foo"); l.Revision != 3 {
+		t.Errorf("have %v, want %v", l.Revision, 3)
+	}
+	fetch(5, "2.0", 4)
+	stable("1.0")
+	fetch(6, "2.0", 4)
+	stable("2.0")
+	pending(0)
+
+	// changes again while pending, that starts over
+	fetch(7, "3.0", 5)
+	fetch(8, "3.1", 6)
+	fetch(9, "3.1", 6)
+	stable("2.0")
+	ps, _ := db.PendingAll(ctx)
+	if have, want := ps[0].Seen, 2; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := ps[0].Since, now.Add(8*time.Minute); !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	fetch(10, "3.1", 6)
+	stable("3.1")
+
+	// only confirmed versions are in the history
+	h, err := db.History(ctx, "Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range h {
+		if v := p.StableVersion; v == "6.6.6" || v == "3.0" {
+			t.Errorf("unconfirmed version %q in the history", v)
+		}
+	}
+}
+
+func TestConfirmingSameFetch(t *testing.T) {
+	ctx := context.Background()
+	var (
+		now = time.Now().UTC()
+		db  = NewConfirming(NewMemory(), ConfirmPolicy{Fetches: 2})
+	)
+	db.Store(ctx, Page{Page: "Foo", T: now, StableVersion: "1.0", Revision: 1})
+
+	// concurrent requests get the same cached fetch
+	p := Page{Page: "Foo", T: now.Add(time.Minute), StableVersion: "6.6.6", Revision: 2}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := db.Store(ctx, p); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if l, _ := db.Last(ctx, "Foo"); l.StableVersion != "1.0" {
+		t.Errorf("have %q, want %q", l.StableVersion, "1.0")
+	}
+	ps, _ := db.PendingAll(ctx)
+	if have, want := len(ps), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if have, want := ps[0].Seen, 1; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// a new fetch does count
+	p.T = now.Add(2 * time.Minute)
+	db.Store(ctx, p)
+	if l, _ := db.Last(ctx, "Foo"); l.StableVersion != "6.6.6" {
+		t.Errorf("have %q, want %q", l.StableVersion, "6.6.6")
+	}
+}
+
+func TestConfirmingAge(t *testing.T) {
+	ctx := context.Background()
+	var (
+		now = time.Now().UTC()
+		db  = NewConfirming(NewMemory(), ConfirmPolicy{Age: time.Hour})
+	)
+	for _, p := range []Page{
+		{Page: "Foo", T: now, StableVersion: "1.0"},
+		{Page: "Foo", T: now.Add(time.Minute), StableVersion: "2.0"},
+		{Page: "Foo", T: now.Add(30 * time.Minute), StableVersion: "2.0"},
+	} {
+		if err := db.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l, _ := db.Last(ctx, "Foo"); l.StableVersion != "1.0" {
+		t.Errorf("have %q, want %q", l.StableVersion, "1.0")
+	}
+
+	db.Store(ctx, Page{Page: "Foo", T: now.Add(61 * time.Minute), StableVersion: "2.0"})
+	if l, _ := db.Last(ctx, "Foo"); l.StableVersion != "2.0" {
+		t.Errorf("have %q, want %q", l.StableVersion, "2.0")
+	}
+}
+
+func TestConfirmingNone(t *testing.T) {
+	ctx := context.Background()
+	db := NewConfirming(NewMemory(), ConfirmPolicy{})
+	now := time.Now().UTC()
+	db.Store(ctx, Page{Page: "Foo", T: now, StableVersion: "1.0"})
+	db.Store(ctx, Page{Page: "Foo", T: now.Add(time.Minute), StableVersion: "2.0"})
+	if l, _ := db.Last(ctx, "Foo"); l.StableVersion != "2.0" {
+		t.Errorf("have %q, want %q", l.StableVersion, "2.0")
+	}
+}
@@ -1,31 +1,109 @@
 package core
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 var ErrCuratedNotFound = errors.New("curated ID not found")
 
 type Page struct {
-	Page          string
-	T             time.Time
-	StableVersion string
-	Homepage      string
+	Page            string
+	T               time.Time // when we saw this version
+	StableVersion   string
+	Releases        []Release // StableVersion, parsed
+	ReleaseDate     time.Time // newest date in Releases. Can be zero.
+	PreviewVersion  string
+	PreviewReleases []Release
+	Homepage        string
+	Wikidata        string // Q-id, if known
+	Developer       string
+	License         string
+	WrittenIn       string
+	OS              string
+	Repository      string
+	// to check for changes
+	ETag         string
+	LastModified string
+	Revision     int64 // wikipedia revision ID
+	// where the values came from
+	RevisionTime time.Time // when Revision was made. Can be zero.
+	Editor       string    // who made Revision
+	Parser       string    // Rules.Version() which found the values
+}
+
+// versionChanged is true if any of the version fields differ
+func versionChanged(a, b Page) bool {
+	return a.StableVersion != b.StableVersion || a.PreviewVersion != b.PreviewVersion
+}
+
+// changed is true if anything we keep history for differs
+func changed(a, b Page) bool {
+	return versionChanged(a, b) ||
+		a.Developer != b.Developer ||
+		a.License != b.License ||
+		a.WrittenIn != b.WrittenIn ||
+		a.OS != b.OS ||
+		a.Repository != b.Repository
 }
 
 type DB interface {
-	Last(string) (*Page, error) // Last spider
-	Recent(int) ([]Page, error)
-	CurrentAll() ([]Page, error)
-	Current(...string) ([]Page, error)
-	History(...string) ([]Page, error) // Newest first
-	Store(Page) error
-	Known() ([]string, error)
-
-	CreateCurated() (string, error)
-	LoadCurated(string) (*Curated, error) // will return (nil, nil) on not found
-	CuratedSetPages(string, []string) error
-	CuratedSetUsed(string) error
-	CuratedSetTitle(string, string) error
+	Last(context.Context, string) (*Page, error) // Last spider
+	Recent(context.Context, int) ([]Page, error)
+	CurrentAll(context.Context) ([]Page, error)
+	Current(context.Context, ...string) ([]Page, error)
+	History(context.Context, ...string) ([]Page, error) // Newest first
+	Store(context.Context, Page) error
+	Known(context.Context) ([]string, error)
+	Search(context.Context, string, int) ([]string, error) // known pages containing the string, matches at the start first
+
+	LoadPending(context.Context, string) (*Pending, error) // will return (nil, nil) on not found
+	SetPending(context.Context, Pending) error
+	DeletePending(context.Context, string) error
+	PendingAll(context.Context) ([]Pending, error) // oldest first
+
+	CreateCurated(context.Context) (string, error)
+	LoadCurated(context.Context, string) (*Curated, error) // will return (nil, nil) on not found
+	CuratedSetPages(context.Context, string, []string) error
+	CuratedSetUsed(context.Context, string) error
+	CuratedSetTitle(context.Context, string, string) error
+}
+
+// Backend is a DB with a schema, such as Postgres and SQLite
+type Backend interface {
+	DB
+	Migrate() (int, int, error) // version before, version now
+	Close()
+}
+
+var (
+	_ Backend = &Postgres{}
+	_ Backend = &SQLite{}
+)
+
+// Open opens a database by URL: "postgresql://..." (or "postgres://...") for
+// Postgres, with at most conns connections, "sqlite:<file>" for SQLite, or
+// "file:<file>" for File. It doesn't migrate.
+func Open(url string, conns int) (Backend, error) {
+	switch {
+	case strings.HasPrefix(url, "postgresql:"), strings.HasPrefix(url, "postgres:"):
+		return NewPostgres(url, conns)
+	case strings.HasPrefix(url, "sqlite:"):
+		file := strings.TrimPrefix(strings.TrimPrefix(url, "sqlite:"), "//")
+		if file == "" {
+			return nil, fmt.Errorf("no file in %q", url)
+		}
+		return NewSQLite(file)
+	case strings.HasPrefix(url, "file:"):
+		file := strings.TrimPrefix(strings.TrimPrefix(url, "file:"), "//")
+		if file == "" {
+			return nil, fmt.Errorf("no file in %q", url)
+		}
+		return NewFile(file)
+	default:
+		return nil, fmt.Errorf("unsupported database URL %q", url)
+	}
 }
@@ -1,13 +1,29 @@
 package core
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
+// tmpDir makes a temporary directory. Call the func to remove it.
+func tmpDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "verssion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
 // InterfaceTestDB is used to test DB implementations
 func InterfaceTestDB(t *testing.T, db DB) {
+	ctx := context.Background()
 	var (
 		now     = time.Now().UTC().Round(time.Second) // PG timestamps are not very precise
 		test1   = "test_1"
@@ -20,14 +36,48 @@ func InterfaceTestDB(t *testing.T, db DB) {
 		test1_2 = Page{
 			Page:          test1,
 			T:             now.Add(-time.Minute),
-			StableVersion: "2.0",
-			Homepage:      "https://test1.example.com",
+			StableVersion: "2.0 / 22 September 2017",
+			Releases: []Release{
+				{Version: "2.0", Date: time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC)},
+			},
+			ReleaseDate:    time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC),
+			PreviewVersion: "3.0-beta1",
+			PreviewReleases: []Release{
+				{Version: "3.0-beta1"},
+			},
+			Homepage:   "https://test1.example.com",
+			Wikidata:   "Q42",
+			Developer:  "Test Foundation",
+			License:    "MIT",
+			WrittenIn:  "Go",
+			OS:         "Linux",
+			Repository: "github.com/example/test1",
 		}
 		test1_3 = Page{
 			Page:          test1,
 			T:             now,
-			StableVersion: "2.0",
-			Homepage:      "https://test1.example.com",
+			StableVersion: "2.0 / 22 September 2017",
+			Releases: []Release{
+				{Version: "2.0", Date: time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC)},
+			},
+			ReleaseDate:    time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC),
+			PreviewVersion: "3.0-beta1",
+			PreviewReleases: []Release{
+				{Version: "3.0-beta1"},
+			},
+			Homepage:     "https://test1.example.com",
+			Wikidata:     "Q42",
+			Developer:    "Test Foundation",
+			License:      "MIT",
+			WrittenIn:    "Go",
+			OS:           "Linux",
+			Repository:   "github.com/example/test1",
+			ETag:         `"1234"`,
+			LastModified: "Sun, 22 Oct 2017 10:00:00 GMT",
+			Revision:     806191967,
+			RevisionTime: time.Date(2017, 10, 22, 9, 58, 0, 0, time.UTC),
+			Editor:       "Example Editor",
+			Parser:       "1-abcdef01",
 		}
 		test2   = "test_2"
 		test2_1 = Page{
@@ -42,26 +92,26 @@ func InterfaceTestDB(t *testing.T, db DB) {
 		test1_3,
 		test2_1,
 	} {
-		if err := db.Store(p); err != nil {
+		if err := db.Store(ctx, p); err != nil {
 			t.Fatal(err)
 		}
 	}
 
 	{
-		l, err := db.Last(test1)
+		l, err := db.Last(ctx, test1)
 		if err != nil {
 			t.Fatal(err)
 		}
 		if l == nil {
 			t.Fatal("unexpected nil")
 		}
-		if have, want := *l, test1_3; have != want {
+		if have, want := *l, test1_3; !reflect.DeepEqual(have, want) {
 			t.Fatalf("have %v, want %v", have, want)
 		}
 	}
 
 	{
-		ls, err := db.Current(test1)
+		ls, err := db.Current(ctx, test1)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -69,7 +119,7 @@ func InterfaceTestDB(t *testing.T, db DB) {
 			t.Fatalf("have %v, want %v", have, want)
 		}
 		// test1_2 is the most recent spider with a change
-		if have, want := ls[0], test1_2; have != want {
+		if have, want := ls[0], test1_2; !reflect.DeepEqual(have, want) {
 			t.Fatalf("have %v, want %v", have, want)
 		}
 	}
@@ -77,8 +127,9 @@ func InterfaceTestDB(t *testing.T, db DB) {
 
 // InterfaceTestCurated is used to test the Curated methods of DB implementations
 func InterfaceTestCurated(t *testing.T, db DB) {
+	ctx := context.Background()
 	{
-		c, err := db.LoadCurated("nosuch")
+		c, err := db.LoadCurated(ctx, "nosuch")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -86,24 +137,24 @@ func InterfaceTestCurated(t *testing.T, db DB) {
 			t.Fatal("want nil")
 		}
 	}
-	id, err := db.CreateCurated()
+	id, err := db.CreateCurated(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if have, want := len(id), 36; have != want {
 		t.Fatalf("have %v, want %v", have, want)
 	}
-	if db.CuratedSetPages(id, []string{"page1", "page2"}); err != nil {
+	if db.CuratedSetPages(ctx, id, []string{"page1", "page2"}); err != nil {
 		t.Fatal(err)
 	}
-	if db.CuratedSetPages(id, []string{"page3", "page2"}); err != nil {
+	if db.CuratedSetPages(ctx, id, []string{"page3", "page2"}); err != nil {
 		t.Fatal(err)
 	}
-	if db.CuratedSetTitle(id, "My first list"); err != nil {
+	if db.CuratedSetTitle(ctx, id, "My first list"); err != nil {
 		t.Fatal(err)
 	}
 
-	c, err := db.LoadCurated(id)
+	c, err := db.LoadCurated(ctx, id)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,10 +179,10 @@ func InterfaceTestCurated(t *testing.T, db DB) {
 
 	// Check SetUsed
 	{
-		if db.CuratedSetUsed(id); err != nil {
+		if db.CuratedSetUsed(ctx, id); err != nil {
 			t.Fatal(err)
 		}
-		c, err := db.LoadCurated(id)
+		c, err := db.LoadCurated(ctx, id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -141,7 +192,7 @@ func InterfaceTestCurated(t *testing.T, db DB) {
 	}
 
 	{
-		id2, err := db.CreateCurated()
+		id2, err := db.CreateCurated(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -150,13 +201,216 @@ func InterfaceTestCurated(t *testing.T, db DB) {
 		}
 	}
 
-	if have, want := db.CuratedSetPages("nosuch", nil), ErrCuratedNotFound; have != want {
-		t.Fatal("have error %v, want error %v", have, want)
+	if have, want := db.CuratedSetPages(ctx, "nosuch", nil), ErrCuratedNotFound; have != want {
+		t.Fatalf("have error %v, want error %v", have, want)
+	}
+	if have, want := db.CuratedSetTitle(ctx, "nosuch", "foo"), ErrCuratedNotFound; have != want {
+		t.Fatalf("have error %v, want error %v", have, want)
+	}
+	if have, want := db.CuratedSetUsed(ctx, "nosuch"), ErrCuratedNotFound; have != want {
+		t.Fatalf("have error %v, want error %v", have, want)
+	}
+}
+
+// InterfaceTestSearch is used to test DB.Search implementations
+func InterfaceTestSearch(t *testing.T, db DB) {
+	ctx := context.Background()
+	now := time.Now().UTC().Round(time.Second)
+	for _, p := range []string{"Glasgow_Haskell_Compiler", "Git_(software)", "Haskell", "100%_Pure"} {
+		if err := db.Store(ctx, Page{Page: p, T: now, StableVersion: "1.0"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for q, want := range map[string][]string{
+		"haskell":   {"Haskell", "Glasgow_Haskell_Compiler"},
+		"HASKELL C": {"Glasgow_Haskell_Compiler"},
+		"g":         {"Git_(software)", "Glasgow_Haskell_Compiler"},
+		"%":         {"100%_Pure"},
+		"_":         {"100%_Pure", "Git_(software)", "Glasgow_Haskell_Compiler"},
+		"nosuch":    nil,
+		"":          nil,
+	} {
+		have, err := db.Search(ctx, q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%q: have %q, want %q", q, have, want)
+		}
+	}
+	have, err := db.Search(ctx, "l", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Glasgow_Haskell_Compiler"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+// InterfaceTestPending is used to test the pending methods of a DB
+func InterfaceTestPending(t *testing.T, db DB) {
+	ctx := context.Background()
+	now := time.Now().UTC().Round(time.Second)
+	p, err := db.LoadPending(ctx, "test_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("have %v, want nil", p)
+	}
+
+	pend1 := Pending{
+		Page: Page{
+			Page:          "test_1",
+			T:             now,
+			StableVersion: "6.6.6",
+			Releases:      []Release{{Version: "6.6.6"}},
+		},
+		Since: now.Add(-time.Hour),
+		Seen:  1,
+	}
+	pend2 := Pending{
+		Page:  Page{Page: "test_2", T: now, StableVersion: "2.0"},
+		Since: now.Add(-2 * time.Hour),
+		Seen:  2,
+	}
+	for _, p := range []Pending{pend1, pend2} {
+		if err := db.SetPending(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pend1.Seen = 2
+	if err := db.SetPending(ctx, pend1); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = db.LoadPending(ctx, "test_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p, &pend1; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	all, err := db.PendingAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := all, []Pending{pend2, pend1}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if err := db.DeletePending(ctx, "test_2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeletePending(ctx, "nosuch"); err != nil {
+		t.Fatal(err)
 	}
-	if have, want := db.CuratedSetTitle("nosuch", "foo"), ErrCuratedNotFound; have != want {
-		t.Fatal("have error %v, want error %v", have, want)
+	all, err = db.PendingAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(all), 1; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+// InterfaceTestConcurrent uses a DB from many goroutines at once. Run it with
+// -race.
+func InterfaceTestConcurrent(t *testing.T, db DB) {
+	ctx := context.Background()
+	const (
+		workers  = 8
+		versions = 10
+	)
+	var (
+		now    = time.Now().UTC().Round(time.Second)
+		shared = "concurrent_shared"
+		wg     sync.WaitGroup
+	)
+	curated, err := db.CreateCurated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			page := fmt.Sprintf("concurrent_%d", w)
+			for i := 0; i < versions; i++ {
+				for _, p := range []Page{
+					{
+						Page:          page,
+						T:             now.Add(time.Duration(i) * time.Minute),
+						StableVersion: fmt.Sprintf("%d.0", i),
+					},
+					{
+						Page:          shared,
+						T:             now.Add(time.Duration(w*versions+i) * time.Second),
+						StableVersion: fmt.Sprintf("%d.%d", w, i),
+					},
+				} {
+					if err := db.Store(ctx, p); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+				if _, err := db.Last(ctx, page); err != nil {
+					t.Error(err)
+				}
+				if _, err := db.Current(ctx, page, shared); err != nil {
+					t.Error(err)
+				}
+				if _, err := db.History(ctx, page); err != nil {
+					t.Error(err)
+				}
+				if _, err := db.Known(ctx); err != nil {
+					t.Error(err)
+				}
+				if err := db.CuratedSetPages(ctx, curated, []string{page, shared}); err != nil {
+					t.Error(err)
+				}
+				if _, err := db.LoadCurated(ctx, curated); err != nil {
+					t.Error(err)
+				}
+				if err := db.CuratedSetUsed(ctx, curated); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		page := fmt.Sprintf("concurrent_%d", w)
+		ps, err := db.History(ctx, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := len(ps), versions; have != want {
+			t.Errorf("%s: have %v, want %v", page, have, want)
+		}
+		last, err := db.Last(ctx, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := last.StableVersion, fmt.Sprintf("%d.0", versions-1); have != want {
+			t.Errorf("%s: have %v, want %v", page, have, want)
+		}
+	}
+	ps, err := db.History(ctx, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(ps), workers*versions; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	c, err := db.LoadCurated(ctx, curated)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if have, want := db.CuratedSetUsed("nosuch"), ErrCuratedNotFound; have != want {
-		t.Fatal("have error %v, want error %v", have, want)
+	if have, want := len(c.Pages), 2; have != want {
+		t.Errorf("have %v, want %v", have, want)
 	}
 }
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrDisambiguation is for wikipedia disambiguation pages, such as "Chef".
+// Candidates are the linked pages, the ones which look like software first.
+type ErrDisambiguation struct {
+	Page       string
+	Candidates []string
+}
+
+func (e ErrDisambiguation) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("%q: disambiguation page", e.Page)
+	}
+	cs := e.Candidates
+	if len(cs) > 3 {
+		cs = append(cs[:3:3], "...")
+	}
+	return fmt.Sprintf("%q: disambiguation page, maybe: %s", e.Page, strings.Join(cs, ", "))
+}
+
+// the box at the bottom, or the category (en, de, fr)
+var disambigRe = regexp.MustCompile(`id="disambigbox"|dmbox-disambig|"wgCategories":\[[^\]]*"(Disambiguation pages|Begriffsklärung|Homonymie)"`)
+
+// isDisambiguation is true for a wikipedia disambiguation page
+func isDisambiguation(body []byte) bool {
+	return disambigRe.Match(body)
+}
+
+// looks like a software page, from the link or the text next to it
+var softwareRe = regexp.MustCompile(`(?i)software|programming|computing|computer|framework|library|database|operating system|browser|configuration management|logiciel|informatique|programmiersprache`)
+
+// namespaces of links which are never candidates (en, de, fr)
+var wikiNamespaces = map[string]bool{
+	"Category":  true,
+	"File":      true,
+	"Help":      true,
+	"Portal":    true,
+	"Special":   true,
+	"Talk":      true,
+	"Template":  true,
+	"Wikipedia": true,
+	"Datei":     true,
+	"Hilfe":     true,
+	"Kategorie": true,
+	"Spezial":   true,
+	"Vorlage":   true,
+	"Aide":      true,
+	"Catégorie": true,
+	"Fichier":   true,
+	"Modèle":    true,
+	"Portail":   true,
+	"Spécial":   true,
+}
+
+// disambiguationCandidates gives the first article link of every list item
+// in the page content. Pages which look like software come first.
+func disambiguationCandidates(body []byte, lang string) []string {
+	type item struct {
+		page string
+		text bytes.Buffer
+	}
+	var (
+		z         = html.NewTokenizer(bytes.NewReader(body))
+		inContent bool
+		open      []*item // open <li>s
+		found     []*item // <li>s with a link, in page order
+		seen      = map[string]bool{}
+	)
+scan:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break scan
+		case html.StartTagToken:
+			tok := z.Token()
+			n := tokenNode(tok)
+			switch {
+			case attr(n, "id") == "mw-content-text":
+				inContent = true
+			case !inContent:
+			case attr(n, "id") == "disambigbox" || attr(n, "id") == "catlinks" ||
+				hasClass(n, "dmbox") || hasClass(n, "printfooter"):
+				// end of the content
+				break scan
+			case tok.Data == "li":
+				open = append(open, &item{})
+			case tok.Data == "a" && len(open) > 0:
+				top := open[len(open)-1]
+				if top.page != "" || hasClass(n, "mw-disambig") {
+					continue
+				}
+				if p := articleLink(attr(n, "href"), lang); p != "" && !seen[p] {
+					seen[p] = true
+					top.page = p
+					found = append(found, top)
+				}
+			}
+		case html.EndTagToken:
+			if tok := z.Token(); tok.Data == "li" && len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case html.TextToken:
+			if len(open) > 0 {
+				open[len(open)-1].text.Write(z.Text())
+			}
+		}
+	}
+
+	var soft, other []string
+	for _, it := range found {
+		if softwareRe.MatchString(it.page) || softwareRe.MatchString(it.text.String()) {
+			soft = append(soft, it.page)
+		} else {
+			other = append(other, it.page)
+		}
+	}
+	return append(soft, other...)
+}
+
+// articleLink gives the page for a "/wiki/Chef_(software)" link, or "" for
+// anything else.
+func articleLink(href, lang string) string {
+	if !strings.HasPrefix(href, "/wiki/") {
+		return ""
+	}
+	title, err := url.PathUnescape(strings.TrimPrefix(href, "/wiki/"))
+	if err != nil {
+		return ""
+	}
+	title, _ = Selector(title) // a section
+	if i := strings.Index(title, ":"); i > 0 && wikiNamespaces[title[:i]] {
+		return ""
+	}
+	if title == "" {
+		return ""
+	}
+	return Canonical(LangPage(lang, title))
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDisambiguation(t *testing.T) {
+	body, err := ioutil.ReadFile("./data/chef.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isDisambiguation(body) {
+		t.Fatal("not a disambiguation page")
+	}
+	want := []string{
+		"Chef_(software)",
+		"Chef_(programming_language)",
+		"Chef_(rapper)",
+		"Chef_Menteur",
+		"Chef_(film)",
+		"Chef_(soundtrack)",
+		"Chef_(South_Park)",
+	}
+	if have := disambiguationCandidates(body, "en"); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	for _, file := range htmlFixtures {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isDisambiguation(b) {
+			t.Errorf("%s: is not a disambiguation page", file)
+		}
+	}
+}
+
+func TestGetPageDisambiguation(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./data/chef.html")
+	}))
+	defer s.Close()
+
+	_, err := GetPage(ctx, "Chef", s.URL)
+	e, ok := err.(ErrDisambiguation)
+	if !ok {
+		t.Fatalf("have %v, want a disambiguation error", err)
+	}
+	if have, want := e.Candidates[0], "Chef_(software)"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if have, want := e.Error(), `"Chef": disambiguation page, maybe: Chef_(software), Chef_(programming_language), Chef_(rapper), ...`; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,441 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSnapshotEvery is how many log entries File writes before it makes a
+// new snapshot.
+const DefaultSnapshotEvery = 10000
+
+// File is a DB which keeps everything in memory, and every change in an
+// append-only log file. On startup the log is replayed. Every SnapshotEvery
+// changes the whole state is written to a snapshot file, and the log starts
+// over.
+//
+// Both files are JSON lines with a fileEvent per line. The log is <path>, and
+// the snapshot is <path>.snapshot. Every change is fsync'ed before it's
+// applied, except for CuratedSetUsed.
+type File struct {
+	mem           *Memory
+	mu            sync.Mutex // serializes changes
+	path          string
+	log           logFile
+	size          int64 // of the log
+	seq           int64 // last sequence number
+	logged        int   // entries in the log
+	SnapshotEvery int
+}
+
+// logFile is an *os.File
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(int64) error
+}
+
+// fileEvent is a single change. Exactly one of the change fields is set.
+type fileEvent struct {
+	Seq           int64    `json:"seq"`
+	Store         *Page    `json:"store,omitempty"`
+	CuratedID     string   `json:"curated_id,omitempty"`
+	Curated       *Curated `json:"curated,omitempty"` // the new state of CuratedID
+	Pending       *Pending `json:"pending,omitempty"`
+	DeletePending string   `json:"delete_pending,omitempty"`
+}
+
+var (
+	_ DB      = &File{}
+	_ Backend = &File{}
+)
+
+// NewFile opens the log at path, and replays it and its snapshot. Both are
+// created if they don't exist.
+func NewFile(path string) (*File, error) {
+	f := &File{
+		mem:           NewMemory(),
+		path:          path,
+		SnapshotEvery: DefaultSnapshotEvery,
+	}
+	if _, err := f.replay(f.snapshotPath(), false); err != nil {
+		return nil, err
+	}
+	n, err := f.replay(path, true)
+	if err != nil {
+		return nil, err
+	}
+	f.logged = n
+
+	lf, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	st, err := lf.Stat()
+	if err != nil {
+		lf.Close()
+		return nil, err
+	}
+	f.log, f.size = lf, st.Size()
+	return f, nil
+}
+
+func (f *File) snapshotPath() string {
+	return f.path + ".snapshot"
+}
+
+// replay applies all events from a file, and returns how many there were.
+// Events which are already in the snapshot are skipped. A log can end with a
+// partial line if we crashed while writing it. That line is cut off.
+func (f *File) replay(path string, isLog bool) (int, error) {
+	fh, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	var (
+		r    = bufio.NewReader(fh)
+		good int64 // offset after the last complete event
+		n    int
+	)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) == 0 {
+				return n, nil
+			}
+			if !isLog {
+				return n, fmt.Errorf("%s: incomplete last line", path)
+			}
+			// torn write
+			return n, os.Truncate(path, good)
+		}
+		if err != nil {
+			return n, err
+		}
+		var ev fileEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return n, fmt.Errorf("%s:%d: %s", path, lineNo, err)
+		}
+		good += int64(len(line))
+		n++
+		if isLog && ev.Seq <= f.seq {
+			continue
+		}
+		if ev.Seq > f.seq {
+			f.seq = ev.Seq
+		}
+		f.apply(ev)
+	}
+}
+
+// apply changes the in-memory state
+func (f *File) apply(ev fileEvent) {
+	ctx := context.Background()
+	switch {
+	case ev.Store != nil:
+		f.mem.Store(ctx, *ev.Store)
+	case ev.Curated != nil:
+		f.mem.mu.Lock()
+		f.mem.curated[ev.CuratedID] = *ev.Curated
+		f.mem.mu.Unlock()
+	case ev.Pending != nil:
+		f.mem.SetPending(ctx, *ev.Pending)
+	case ev.DeletePending != "":
+		f.mem.DeletePending(ctx, ev.DeletePending)
+	}
+}
+
+// write logs and applies an event. f.mu must be held. A failed write is
+// cut off the log again, so later events don't end up after half a line.
+func (f *File) write(ev fileEvent) error {
+	ev.Seq = f.seq + 1
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	line := append(b, '\n')
+	if _, err := f.log.Write(line); err != nil {
+		return f.undo(err)
+	}
+	if err := f.log.Sync(); err != nil {
+		return f.undo(err)
+	}
+	f.size += int64(len(line))
+	f.seq = ev.Seq
+	f.apply(ev)
+
+	f.logged++
+	if f.SnapshotEvery > 0 && f.logged >= f.SnapshotEvery {
+		// the event is safe in the log, we'll try again on the next one
+		if err := f.snapshot(); err != nil {
+			log.Printf("%s: snapshot: %s", f.path, err)
+		}
+	}
+	return nil
+}
+
+// undo truncates the log to before a failed write
+func (f *File) undo(err error) error {
+	if terr := f.log.Truncate(f.size); terr != nil {
+		return fmt.Errorf("%s (truncate: %s)", err, terr)
+	}
+	return err
+}
+
+// Snapshot writes the whole state to the snapshot file, and empties the log.
+func (f *File) Snapshot() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshot()
+}
+
+// snapshot writes a new snapshot next to the old one, and renames it. Only
+// then the log is emptied. If we crash in between the log is replayed on top
+// of the new snapshot, which skips everything the snapshot already has.
+func (f *File) snapshot() error {
+	tmp := f.snapshotPath() + ".tmp"
+	fh, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fh)
+	enc := json.NewEncoder(w)
+	for _, ev := range f.state() {
+		if err := enc.Encode(ev); err != nil {
+			fh.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fh.Close()
+		return err
+	}
+	if err := fh.Sync(); err != nil {
+		fh.Close()
+		return err
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.snapshotPath()); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return err
+	}
+
+	if err := f.log.Truncate(0); err != nil {
+		return err
+	}
+	f.size = 0
+	if err := f.log.Sync(); err != nil {
+		return err
+	}
+	f.logged = 0
+	return nil
+}
+
+// state has events which recreate everything. They all have the current
+// sequence number, so nothing in the log is applied twice. The first event
+// is empty, so even an empty snapshot has the sequence number.
+func (f *File) state() []fileEvent {
+	m := f.mem
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	evs := []fileEvent{{}}
+	for i := range m.hist {
+		evs = append(evs, fileEvent{Store: &m.hist[i]})
+	}
+	var ids []string
+	for id := range m.curated {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := m.curated[id]
+		evs = append(evs, fileEvent{CuratedID: id, Curated: &c})
+	}
+	var pages []string
+	for p := range m.pending {
+		pages = append(pages, p)
+	}
+	sort.Strings(pages)
+	for _, p := range pages {
+		pend := m.pending[p]
+		evs = append(evs, fileEvent{Pending: &pend})
+	}
+	for i := range evs {
+		evs[i].Seq = f.seq
+	}
+	return evs
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Migrate is a no-op; there is no schema.
+func (f *File) Migrate() (int, int, error) {
+	return 0, 0, nil
+}
+
+// Close snapshots and closes the log. A failed snapshot is logged; the log
+// still has everything.
+func (f *File) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.snapshot(); err != nil {
+		log.Printf("%s: snapshot: %s", f.path, err)
+	}
+	if err := f.log.Close(); err != nil {
+		log.Printf("%s: %s", f.path, err)
+	}
+}
+
+func (f *File) Last(ctx context.Context, page string) (*Page, error) {
+	p, err := f.mem.Last(ctx, page)
+	if _, ok := err.(ErrNotFound); ok {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (f *File) Recent(ctx context.Context, n int) ([]Page, error) {
+	return f.mem.Recent(ctx, n)
+}
+
+func (f *File) CurrentAll(ctx context.Context) ([]Page, error) {
+	return f.mem.CurrentAll(ctx)
+}
+
+func (f *File) Current(ctx context.Context, pages ...string) ([]Page, error) {
+	return f.mem.Current(ctx, pages...)
+}
+
+func (f *File) History(ctx context.Context, pages ...string) ([]Page, error) {
+	return f.mem.History(ctx, pages...)
+}
+
+func (f *File) Store(_ context.Context, p Page) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write(fileEvent{Store: &p})
+}
+
+func (f *File) Known(ctx context.Context) ([]string, error) {
+	return f.mem.Known(ctx)
+}
+
+func (f *File) Search(ctx context.Context, q string, limit int) ([]string, error) {
+	return f.mem.Search(ctx, q, limit)
+}
+
+func (f *File) LoadPending(ctx context.Context, page string) (*Pending, error) {
+	return f.mem.LoadPending(ctx, page)
+}
+
+func (f *File) SetPending(_ context.Context, p Pending) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write(fileEvent{Pending: &p})
+}
+
+func (f *File) DeletePending(ctx context.Context, page string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, _ := f.mem.LoadPending(ctx, page); p == nil {
+		return nil
+	}
+	return f.write(fileEvent{DeletePending: page})
+}
+
+func (f *File) PendingAll(ctx context.Context) ([]Pending, error) {
+	return f.mem.PendingAll(ctx)
+}
+
+func (f *File) CreateCurated(_ context.Context) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	t := time.Now().UTC()
+	ids := id.String()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return ids, f.write(fileEvent{
+		CuratedID: ids,
+		Curated: &Curated{
+			Created:     t,
+			LastUpdated: t,
+			LastUsed:    t,
+		},
+	})
+}
+
+func (f *File) LoadCurated(ctx context.Context, id string) (*Curated, error) {
+	return f.mem.LoadCurated(ctx, id)
+}
+
+func (f *File) CuratedSetPages(ctx context.Context, id string, pages []string) error {
+	return f.updateCurated(ctx, id, func(c *Curated) {
+		c.Pages = append([]string(nil), pages...)
+		sort.Strings(c.Pages)
+		c.LastUpdated = time.Now().UTC()
+	})
+}
+
+// CuratedSetUsed only changes the in-memory state. It's called on every feed
+// poll, which isn't worth an fsync. LastUsed is written with the next snapshot
+// or change of the list, and lost if we crash before that.
+func (f *File) CuratedSetUsed(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mem.CuratedSetUsed(ctx, id)
+}
+
+func (f *File) CuratedSetTitle(ctx context.Context, id, title string) error {
+	return f.updateCurated(ctx, id, func(c *Curated) {
+		c.CustomTitle = title
+		c.LastUpdated = time.Now().UTC()
+	})
+}
+
+// updateCurated logs the changed version of a curated list
+func (f *File) updateCurated(ctx context.Context, id string, change func(*Curated)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.mem.LoadCurated(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return ErrCuratedNotFound
+	}
+	change(c)
+	return f.write(fileEvent{CuratedID: id, Curated: c})
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tmpFile is a path in a new temporary directory. Call the func to remove it.
+func tmpFile(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, remove := tmpDir(t)
+	return filepath.Join(dir, "verssion.log"), remove
+}
+
+// newFile is an empty File. Call the func to close and remove it.
+func newFile(t *testing.T) (*File, func()) {
+	t.Helper()
+	path, remove := tmpFile(t)
+	f, err := NewFile(path)
+	if err != nil {
+		remove()
+		t.Fatal(err)
+	}
+	return f, func() {
+		f.Close()
+		remove()
+	}
+}
+
+func TestFileDB(t *testing.T) {
+	f, done := newFile(t)
+	defer done()
+	InterfaceTestDB(t, f)
+}
+
+func TestFileCurated(t *testing.T) {
+	f, done := newFile(t)
+	defer done()
+	InterfaceTestCurated(t, f)
+}
+
+func TestFileSearch(t *testing.T) {
+	f, done := newFile(t)
+	defer done()
+	InterfaceTestSearch(t, f)
+}
+
+func TestFilePending(t *testing.T) {
+	f, done := newFile(t)
+	defer done()
+	InterfaceTestPending(t, f)
+}
+
+func TestFileConcurrent(t *testing.T) {
+	f, done := newFile(t)
+	defer done()
+	f.SnapshotEvery = 50
+	InterfaceTestConcurrent(t, f)
+}
+
+func TestFileReplay(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		path, done = tmpFile(t)
+		now        = time.Now().UTC().Round(time.Second)
+	)
+	defer done()
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SnapshotEvery = 3
+	for i, v := range []string{"1.0", "1.1", "1.1", "2.0"} {
+		p := Page{Page: "Git", T: now.Add(time.Duration(i) * time.Minute), StableVersion: v}
+		if err := f.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id, err := f.CreateCurated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CuratedSetPages(ctx, id, []string{"Git"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPending(ctx, Pending{Page: Page{Page: "Git", StableVersion: "3.0"}, Since: now, Seen: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// no Close(): as if we crashed
+
+	check := func(t *testing.T, g *File) {
+		t.Helper()
+		have, err := g.History(ctx, "Git")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := f.History(ctx, "Git")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		c, err := g.LoadCurated(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c == nil {
+			t.Fatal("no curated")
+		}
+		if have, want := c.Pages, []string{"Git"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		pend, err := g.LoadPending(ctx, "Git")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pend == nil || pend.Page.StableVersion != "3.0" {
+			t.Errorf("have %v, want pending 3.0", pend)
+		}
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		g, err := NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, g)
+	})
+
+	t.Run("torn write", func(t *testing.T) {
+		lf, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lf.Write([]byte(`{"seq":99,"store":{"Page":"Gi`))
+		lf.Close()
+
+		g, err := NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, g)
+	})
+
+	t.Run("crash after snapshot", func(t *testing.T) {
+		// the log is from before the snapshot
+		log, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Snapshot(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, log, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, g)
+	})
+}
+
+// halfLog writes half of every line, and then fails
+type halfLog struct {
+	*os.File
+}
+
+func (l halfLog) Write(b []byte) (int, error) {
+	n, _ := l.File.Write(b[:len(b)/2])
+	return n, errors.New("disk full")
+}
+
+func TestFileFailedWrite(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		path, done = tmpFile(t)
+		now        = time.Now().UTC().Round(time.Second)
+	)
+	defer done()
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Store(ctx, Page{Page: "Git", T: now, StableVersion: "1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	lf := f.log
+	f.log = halfLog{lf.(*os.File)}
+	if err := f.Store(ctx, Page{Page: "Git", T: now.Add(time.Minute), StableVersion: "6.6.6"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	f.log = lf
+	if err := f.Store(ctx, Page{Page: "Git", T: now.Add(2 * time.Minute), StableVersion: "2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	// no Close(): as if we crashed
+
+	g, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := g.History(ctx, "Git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, p := range h {
+		have = append(have, p.StableVersion)
+	}
+	if want := []string{"2.0", "1.0"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestFileFailedSnapshot(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		path, done = tmpFile(t)
+	)
+	defer done()
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SnapshotEvery = 1
+	// the snapshot can't be written
+	if err := os.Mkdir(path+".snapshot.tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Store(ctx, Page{Page: "Git", T: time.Now().UTC(), StableVersion: "1.0"}); err != nil {
+		t.Fatalf("change is in the log, but: %s", err)
+	}
+
+	g, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := g.Last(ctx, "Git"); p == nil || p.StableVersion != "1.0" {
+		t.Errorf("have %v, want 1.0", p)
+	}
+}
+
+func TestFileCuratedUsed(t *testing.T) {
+	ctx := context.Background()
+	path, done := tmpFile(t)
+	defer done()
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := f.CreateCurated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged := f.logged
+	if err := f.CuratedSetUsed(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	// not logged
+	if have, want := f.logged, logged; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	c, err := f.LoadCurated(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// but in the snapshot
+	g, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	c2, err := g.LoadCurated(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := c2.LastUsed, c.LastUsed; !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"io"
 	"strings"
 	"unicode"
@@ -9,7 +10,13 @@ import (
 )
 
 type Table struct {
-	Rows [][]string
+	// Caption is the title of an infobox, such as "PostgreSQL". Only set
+	// by FindInfoboxes and ScanInfobox(es).
+	Caption string
+	Rows    [][]string
+	// Nested has the first table found in a row, by row index. Nil if
+	// there are no nested tables.
+	Nested map[int]Table
 }
 
 // FindTables returns all top-level tables
@@ -18,7 +25,10 @@ func FindTables(r io.Reader) ([]Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	return findTables(doc)
+}
 
+func findTables(doc *html.Node) ([]Table, error) {
 	var ts []Table
 	var f func(*html.Node) error
 	f = func(n *html.Node) error {
@@ -49,6 +59,13 @@ func tTable(n *html.Node) (*Table, error) {
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
 			if c.Type == html.ElementNode && c.Data == "tr" {
 				if r := tRow(c); r != nil {
+					if n := findTable(c); n != nil {
+						if tab.Nested == nil {
+							tab.Nested = map[int]Table{}
+						}
+						nt, _ := tTable(n)
+						tab.Nested[len(tab.Rows)] = *nt
+					}
 					tab.Rows = append(tab.Rows, r)
 				}
 				continue
@@ -60,6 +77,19 @@ func tTable(n *html.Node) (*Table, error) {
 	return tab, nil
 }
 
+// findTable gives the first table below n
+func findTable(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "table" {
+			return c
+		}
+		if t := findTable(c); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
 func tRow(n *html.Node) []string {
 	var row []string
 	var f func(*html.Node)
@@ -77,31 +107,35 @@ func tRow(n *html.Node) []string {
 }
 
 func tString(n *html.Node) string {
-	res := ""
+	var b bytes.Buffer
+	writeString(&b, n)
+	return b.String()
+}
+
+func writeString(b *bytes.Buffer, n *html.Node) {
 node:
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
 		switch c.Type {
 		case html.TextNode:
-			res += stripCtl(c.Data)
+			b.WriteString(stripCtl(c.Data))
 		case html.ElementNode:
 			switch c.Data {
 			case "small", "sup":
 				continue node
 			case "br":
-				res += "\n"
+				b.WriteByte('\n')
 			default:
 				if !ignoreTag(c.Data, c.Attr) {
-					res += tString(c)
+					writeString(b, c)
 				}
 				if c.Data == "tr" {
-					res += "\n"
+					b.WriteByte('\n')
 				}
 			}
 		default:
-			res += tString(c)
+			writeString(b, c)
 		}
 	}
-	return res
 }
 
 // ignore certain HTML elemens. Specific to wikipedia
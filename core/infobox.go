@@ -0,0 +1,188 @@
+package core
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FindInfoboxes returns the top-level `table.infobox` elements. Every row is
+// either a label and its value ("Stable release", "2.14.2"), or a single
+// cell (headers, images). Labels are `th.infobox-label` cells, or, in tables
+// without any of those, any `th` followed by a `td`. A label with a rowspan
+// gets the values of all its rows, one per line. A label which spans the
+// whole row gets the `td.infobox-data` from the next row. Nested tables are
+// in Table.Nested, like FindTables. The caption is the <caption>, or else
+// the header in the first row.
+func FindInfoboxes(r io.Reader) ([]Table, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return findInfoboxes(doc), nil
+}
+
+func findInfoboxes(doc *html.Node) []Table {
+	var (
+		ts []Table
+		f  func(*html.Node)
+	)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" && hasClass(n, "infobox") {
+			ts = append(ts, infoboxTable(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+	return ts
+}
+
+func infoboxTable(n *html.Node) Table {
+	var (
+		tab        Table
+		spanLeft   int  // rows left for the label with a rowspan
+		labelAbove bool // previous row is a label without a value
+		trs        = tableRows(n)
+		rows       [][]*html.Node
+		classes    bool // there are th.infobox-label cells
+	)
+	if c := childElement(n, "caption"); c != nil {
+		tab.Caption = cellString(c)
+	}
+	for _, tr := range trs {
+		var cells []*html.Node
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "th" || c.Data == "td") {
+				cells = append(cells, c)
+				classes = classes || (c.Data == "th" && hasClass(c, "infobox-label"))
+			}
+		}
+		rows = append(rows, cells)
+	}
+	for i, tr := range trs {
+		cells := rows[i]
+		if len(cells) == 0 {
+			continue
+		}
+
+		if len(tab.Rows) == 0 && tab.Caption == "" && len(cells) == 1 && cells[0].Data == "th" && !hasClass(cells[0], "infobox-label") {
+			tab.Caption = cellString(cells[0])
+		}
+
+		var row []string
+		switch {
+		case isLabel(cells, classes):
+			row = []string{cellString(cells[0]), cellString(cells[1])}
+			spanLeft = intAttr(cells[0], "rowspan", 1) - 1
+		case labelAbove && len(cells) == 1 && cells[0].Data == "td" && hasClass(cells[0], "infobox-data"):
+			// the value of the label above, with a colspan
+			labelAbove = false
+			last := len(tab.Rows) - 1
+			tab.Rows[last] = append(tab.Rows[last], cellString(cells[0]))
+			if nt := findTable(tr); nt != nil {
+				tab.setNested(last, nt)
+			}
+			continue
+		case spanLeft > 0 && len(tab.Rows) > 0 && cells[0].Data == "td":
+			// more values for the previous label
+			spanLeft--
+			prev := tab.Rows[len(tab.Rows)-1]
+			if v := cellString(cells[0]); v != "" && len(prev) > 1 {
+				prev[1] = strings.TrimSpace(prev[1] + "\n" + v)
+			}
+			continue
+		default:
+			spanLeft = 0
+			for _, c := range cells {
+				row = append(row, cellString(c))
+			}
+		}
+		labelAbove = classes && len(cells) == 1 && cells[0].Data == "th" && hasClass(cells[0], "infobox-label")
+		if nt := findTable(tr); nt != nil {
+			tab.setNested(len(tab.Rows), nt)
+		}
+		tab.Rows = append(tab.Rows, row)
+	}
+	return tab
+}
+
+func (tab *Table) setNested(i int, n *html.Node) {
+	if tab.Nested == nil {
+		tab.Nested = map[int]Table{}
+	}
+	t, _ := tTable(n)
+	tab.Nested[i] = *t
+}
+
+// isLabel is true for `th.infobox-label` + `td` rows. Older pages have no
+// classes, and use any `th` + `td`. classes tells whether the table has
+// `th.infobox-label` cells at all.
+func isLabel(cells []*html.Node, classes bool) bool {
+	if len(cells) < 2 || cells[0].Data != "th" || cells[1].Data != "td" {
+		return false
+	}
+	if classes {
+		return hasClass(cells[0], "infobox-label")
+	}
+	return attr(cells[0], "scope") == "row" || intAttr(cells[0], "colspan", 1) == 1
+}
+
+// tableRows are the rows of this table, but not of nested tables
+func tableRows(n *html.Node) []*html.Node {
+	var rows []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "tr":
+			rows = append(rows, c)
+		case "thead", "tbody", "tfoot":
+			rows = append(rows, tableRows(c)...)
+		}
+	}
+	return rows
+}
+
+func childElement(n *html.Node, tag string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+func cellString(n *html.Node) string {
+	return cleanSpace(tString(n))
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func intAttr(n *html.Node, key string, def int) int {
+	if i, err := strconv.Atoi(attr(n, key)); err == nil && i > 0 {
+		return i
+	}
+	return def
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFindInfoboxes(t *testing.T) {
+	type cas struct {
+		Html string
+		Want []Table
+	}
+	cases := []cas{
+		{
+			// not an infobox
+			Html: `<table><tr><th>Latest release</th><td>1.0</td></tr></table>`,
+			Want: nil,
+		},
+		{
+			Html: `<table class="infobox vevent">
+<tr><th colspan="2" class="infobox-above">Foo</th></tr>
+<tr><th scope="row" class="infobox-label">Stable release</th><td class="infobox-data">1.2.3</td></tr>
+</table>`,
+			Want: []Table{
+				{
+					Caption: "Foo",
+					Rows: [][]string{
+						{"Foo"},
+						{"Stable release", "1.2.3"},
+					},
+				},
+			},
+		},
+		{
+			// rowspan label
+			Html: `<table class="infobox"><tbody>
+<tr><th rowspan="2" class="infobox-label">Stable release(s)</th><td class="infobox-data">1.2</td></tr>
+<tr><td class="infobox-data">2.0</td></tr>
+<tr><td colspan="2">footer</td></tr>
+</tbody></table>`,
+			Want: []Table{
+				{
+					Rows: [][]string{
+						{"Stable release(s)", "1.2\n2.0"},
+						{"footer"},
+					},
+				},
+			},
+		},
+		{
+			// with label classes only those are labels
+			Html: `<table class="infobox">
+<tr><th rowspan="2" class="infobox-header">Header</th><td>not a label</td></tr>
+<tr><td>own row</td></tr>
+<tr><th scope="row" class="infobox-label">License</th><td class="infobox-data">MIT</td></tr>
+</table>`,
+			Want: []Table{
+				{
+					Rows: [][]string{
+						{"Header", "not a label"},
+						{"own row"},
+						{"License", "MIT"},
+					},
+				},
+			},
+		},
+		{
+			// a label spanning the row, with its value in the next row
+			Html: `<table class="infobox">
+<tr><th colspan="2" class="infobox-label">Stable release(s)</th></tr>
+<tr><td colspan="2" class="infobox-data"><table><tr><th>Standard</th><td>1.0</td></tr></table></td></tr>
+<tr><td colspan="2" class="infobox-full-data">footer</td></tr>
+</table>`,
+			Want: []Table{
+				{
+					Rows: [][]string{
+						{"Stable release(s)", "Standard1.0"},
+						{"footer"},
+					},
+					Nested: map[int]Table{
+						0: {Rows: [][]string{{"Standard", "1.0"}}},
+					},
+				},
+			},
+		},
+		{
+			// nested table rows stay in the nested table
+			Html: `<table class="infobox">
+<tr><td colspan="2"><table class="infobox"><tr><th>Standard</th><td>1.0</td></tr></table></td></tr>
+<tr><th>License</th><td>MIT</td></tr>
+</table>`,
+			Want: []Table{
+				{
+					Rows: [][]string{
+						{"Standard1.0"},
+						{"License", "MIT"},
+					},
+					Nested: map[int]Table{
+						0: {Rows: [][]string{{"Standard", "1.0"}}},
+					},
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		d, err := FindInfoboxes(bytes.NewBufferString(c.Html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := d, c.Want; !reflect.DeepEqual(have, want) {
+			t.Errorf("case %d: have %#v, want %#v", i, have, want)
+		}
+	}
+}
+
+func TestFindInfoboxesReal(t *testing.T) {
+	for file, want := range map[string][]string{
+		"./data/debian.html":        {"Debian"},
+		"./data/firefox.html":       {"Mozilla Firefox"},
+		"./data/git.html":           {"Git"},
+		"./data/pine.html":          {"Pine"},
+		"./data/postgresql.html":    {"PostgreSQL", "PostgreSQL License"},
+		"./data/python.html":        {"Python"},
+		"./data/de_postgresql.html": {"PostgreSQL"},
+		"./data/fr_postgresql.html": nil, // no table.infobox, uses FindTables
+		"./data/netsurf.html":       {"NetSurf"},
+		"./data/de_firefox.html":    {"Mozilla Firefox"},
+		"./data/de_netscape.html":   {"Netscape Navigator"},
+		"./data/fr_firefox.html":    nil,
+	} {
+		r, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := FindInfoboxes(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, t := range ts {
+			have = append(have, t.Caption)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%s: have %q, want %q", file, have, want)
+		}
+	}
+}
+
+func TestParseIgnoresOtherTables(t *testing.T) {
+	page := `<table class="wikitable"><tr><th>Stable release</th><td>9.9</td></tr></table>
+<table class="infobox"><tr><th scope="row">Stable release</th><td>1.2.3</td></tr></table>`
+	ib, err := DefaultRules.Parse(bytes.NewBufferString(page), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ib.Stable, "1.2.3"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"strings"
+)
+
+// Pages on other wikipedias than the English one are prefixed with their
+// language: "de:PostgreSQL". English pages have no prefix.
+
+// DefaultLanguage is the wikipedia used for pages without a language prefix
+const DefaultLanguage = "en"
+
+// languages are the supported wikipedias
+var languages = map[string]bool{
+	"de": true,
+	"en": true,
+	"fr": true,
+}
+
+// Languages are the supported wikipedia languages
+func Languages() []string {
+	return []string{"de", "en", "fr"}
+}
+
+// Language splits "de:PostgreSQL" into "de" and "PostgreSQL". Pages without
+// a known language prefix are English.
+func Language(page string) (string, string) {
+	if i := strings.Index(page, ":"); i > 0 {
+		if languages[page[:i]] {
+			return page[:i], page[i+1:]
+		}
+	}
+	return DefaultLanguage, page
+}
+
+// LangPage is the inverse of Language: LangPage("de", "PostgreSQL") gives
+// "de:PostgreSQL". English pages don't get a prefix.
+func LangPage(lang, title string) string {
+	if lang == DefaultLanguage || lang == "" {
+		return title
+	}
+	return lang + ":" + title
+}
+
+// WikiURL is the URL of a wikipedia article. Any infobox selector is ignored.
+func WikiURL(page string) string {
+	page, _ = Selector(page)
+	lang, title := Language(page)
+	return "https://" + lang + ".wikipedia.org/wiki/" + title
+}
+
+// months in other languages, for parseDate
+var months = strings.NewReplacer(
+	// German
+	"Januar ", "January ",
+	"Jänner ", "January ",
+	"Februar ", "February ",
+	"März ", "March ",
+	"Mai ", "May ",
+	"Juni ", "June ",
+	"Juli ", "July ",
+	"Oktober ", "October ",
+	"Dezember ", "December ",
+	// French
+	"janvier ", "January ",
+	"février ", "February ",
+	"mars ", "March ",
+	"avril ", "April ",
+	"mai ", "May ",
+	"juin ", "June ",
+	"juillet ", "July ",
+	"août ", "August ",
+	"septembre ", "September ",
+	"octobre ", "October ",
+	"novembre ", "November ",
+	"décembre ", "December ",
+)
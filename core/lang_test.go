@@ -0,0 +1,40 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	type cas struct {
+		Page, Lang, Title string
+	}
+	for _, c := range []cas{
+		{"PostgreSQL", "en", "PostgreSQL"},
+		{"de:PostgreSQL", "de", "PostgreSQL"},
+		{"fr:Git_(logiciel)", "fr", "Git_(logiciel)"},
+		{"xx:Foo", "en", "xx:Foo"},
+		{"Star_Wars:_Battlefront", "en", "Star_Wars:_Battlefront"},
+	} {
+		lang, title := Language(c.Page)
+		if have, want := lang, c.Lang; have != want {
+			t.Errorf("%q: have %q, want %q", c.Page, have, want)
+		}
+		if have, want := title, c.Title; have != want {
+			t.Errorf("%q: have %q, want %q", c.Page, have, want)
+		}
+		if have, want := LangPage(lang, title), c.Page; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
+
+func TestWikiURL(t *testing.T) {
+	for page, want := range map[string]string{
+		"PostgreSQL":    "https://en.wikipedia.org/wiki/PostgreSQL",
+		"de:PostgreSQL": "https://de.wikipedia.org/wiki/PostgreSQL",
+	} {
+		if have := WikiURL(page); have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package core
 
 import (
+	"context"
 	"sort"
 	"sync"
 	"time"
@@ -12,24 +13,31 @@ import (
 
 type Memory struct {
 	hist    []Page
+	byPage  map[string][]int // indexes in hist
 	current map[string]Page
 	mu      sync.Mutex
 	curated map[string]Curated
+	pending map[string]Pending
 }
 
 func NewMemory() *Memory {
 	return &Memory{
+		byPage:  map[string][]int{},
 		current: map[string]Page{},
 		curated: map[string]Curated{},
+		pending: map[string]Pending{},
 	}
 }
 
 var _ DB = NewMemory()
 
-func (m *Memory) Last(page string) (*Page, error) {
+func (m *Memory) Last(_ context.Context, page string) (*Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	var last Page
-	for _, p := range m.hist {
-		if p.Page == page && p.T.After(last.T) {
+	for _, i := range m.byPage[page] {
+		if p := m.hist[i]; p.T.After(last.T) {
 			last = p
 		}
 	}
@@ -39,9 +47,9 @@ func (m *Memory) Last(page string) (*Page, error) {
 	return nil, ErrNotFound{Page: page}
 }
 
-func (m *Memory) Recent(n int) ([]Page, error) {
+func (m *Memory) Recent(ctx context.Context, n int) ([]Page, error) {
 	// TODO: this is not right
-	h, err := m.CurrentAll()
+	h, err := m.CurrentAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +59,7 @@ func (m *Memory) Recent(n int) ([]Page, error) {
 	return h, nil
 }
 
-func (m *Memory) CurrentAll() ([]Page, error) {
+func (m *Memory) CurrentAll(_ context.Context) ([]Page, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -62,7 +70,7 @@ func (m *Memory) CurrentAll() ([]Page, error) {
 	return ps, nil
 }
 
-func (m *Memory) Current(pages ...string) ([]Page, error) {
+func (m *Memory) Current(_ context.Context, pages ...string) ([]Page, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -75,33 +83,44 @@ func (m *Memory) Current(pages ...string) ([]Page, error) {
 	return ps, nil
 }
 
-func (m *Memory) History(pages ...string) ([]Page, error) {
-	var ps []Page
-	for _, p := range m.hist {
-		for _, pn := range pages {
-			if p.Page == pn {
-				ps = append(ps, p)
-				break
-			}
+// History of pages. Newest first.
+func (m *Memory) History(_ context.Context, pages ...string) ([]Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		is   []int
+		seen = map[string]bool{}
+	)
+	for _, pn := range pages {
+		if !seen[pn] {
+			seen[pn] = true
+			is = append(is, m.byPage[pn]...)
 		}
 	}
+	sort.Sort(sort.Reverse(sort.IntSlice(is)))
+	var ps []Page
+	for _, i := range is {
+		ps = append(ps, m.hist[i])
+	}
 	return ps, nil
 }
 
-func (m *Memory) Store(p Page) error {
-	m.hist = append(m.hist, p)
-
+func (m *Memory) Store(_ context.Context, p Page) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+
+	m.byPage[p.Page] = append(m.byPage[p.Page], len(m.hist))
+	m.hist = append(m.hist, p)
 	old, ok := m.current[p.Page]
-	if !ok || old.StableVersion != p.StableVersion {
+	if !ok || changed(old, p) {
 		m.current[p.Page] = p
 	}
 
 	return nil
 }
 
-func (m *Memory) Known() ([]string, error) {
+func (m *Memory) Known(_ context.Context) ([]string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -112,7 +131,15 @@ func (m *Memory) Known() ([]string, error) {
 	return ps, nil
 }
 
-func (m *Memory) CreateCurated() (string, error) {
+func (m *Memory) Search(ctx context.Context, q string, limit int) ([]string, error) {
+	ps, err := m.Known(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return search(ps, q, limit), nil
+}
+
+func (m *Memory) CreateCurated(_ context.Context) (string, error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return "", err
@@ -130,7 +157,7 @@ func (m *Memory) CreateCurated() (string, error) {
 	return ids, nil
 }
 
-func (m *Memory) LoadCurated(id string) (*Curated, error) {
+func (m *Memory) LoadCurated(_ context.Context, id string) (*Curated, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	c, ok := m.curated[id]
@@ -140,7 +167,7 @@ func (m *Memory) LoadCurated(id string) (*Curated, error) {
 	return &c, nil
 }
 
-func (m *Memory) CuratedSetPages(id string, pages []string) error {
+func (m *Memory) CuratedSetPages(_ context.Context, id string, pages []string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -154,7 +181,7 @@ func (m *Memory) CuratedSetPages(id string, pages []string) error {
 	return nil
 }
 
-func (m *Memory) CuratedSetUsed(id string) error {
+func (m *Memory) CuratedSetUsed(_ context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -167,7 +194,7 @@ func (m *Memory) CuratedSetUsed(id string) error {
 	return nil
 }
 
-func (m *Memory) CuratedSetTitle(id string, title string) error {
+func (m *Memory) CuratedSetTitle(_ context.Context, id string, title string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -179,3 +206,47 @@ func (m *Memory) CuratedSetTitle(id string, title string) error {
 	m.curated[id] = c
 	return nil
 }
+
+func (m *Memory) LoadPending(_ context.Context, page string) (*Pending, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.pending[page]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (m *Memory) SetPending(_ context.Context, p Pending) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending[p.Page.Page] = p
+	return nil
+}
+
+func (m *Memory) DeletePending(_ context.Context, page string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pending, page)
+	return nil
+}
+
+func (m *Memory) PendingAll(_ context.Context) ([]Pending, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ps []Pending
+	for _, p := range m.pending {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if !ps[i].Since.Equal(ps[j].Since) {
+			return ps[i].Since.Before(ps[j].Since)
+		}
+		return ps[i].Page.Page < ps[j].Page.Page
+	})
+	return ps, nil
+}
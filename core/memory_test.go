@@ -1,7 +1,11 @@
 package core
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMemoryDB(t *testing.T) {
@@ -13,3 +17,46 @@ func TestMemoryCurated(t *testing.T) {
 	m := NewMemory()
 	InterfaceTestCurated(t, m)
 }
+
+func TestMemorySearch(t *testing.T) {
+	m := NewMemory()
+	InterfaceTestSearch(t, m)
+}
+
+func TestMemoryPending(t *testing.T) {
+	m := NewMemory()
+	InterfaceTestPending(t, m)
+}
+
+func TestMemoryConcurrent(t *testing.T) {
+	m := NewMemory()
+	InterfaceTestConcurrent(t, m)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	now := time.Now()
+	for i, p := range []string{"a", "b", "a", "c", "b"} {
+		m.Store(ctx, Page{Page: p, T: now.Add(time.Duration(i) * time.Minute), StableVersion: fmt.Sprintf("%s%d", p, i)})
+	}
+	h, err := m.History(ctx, "b", "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, p := range h {
+		have = append(have, p.StableVersion)
+	}
+	if want := []string{"b4", "a2", "b1", "a0"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	l, err := m.Last(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := l.StableVersion, "a2"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
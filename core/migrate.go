@@ -0,0 +1,233 @@
+package core
+
+import (
+	"fmt"
+)
+
+// migrations are applied in order, each in its own transaction. Version n is
+// migrations[n-1]. Only ever append to this list.
+// They are written so they also work on databases made with older versions of
+// tables.sql.
+var migrations = []string{
+	// 1: the original tables
+	`
+CREATE TABLE IF NOT EXISTS page
+    ( page text NOT NULL
+    , timestamp timestamptz NOT NULL
+    , stable_version text NOT NULL
+    , homepage text NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS page_page ON page (page, timestamp);
+
+DROP VIEW IF EXISTS current;
+DROP VIEW IF EXISTS updates;
+
+CREATE VIEW updates
+AS SELECT page, timestamp, stable_version, homepage
+    FROM (
+        SELECT page, timestamp, stable_version, homepage, lag(stable_version) OVER (
+            PARTITION BY page ORDER BY timestamp
+        ) AS prev
+        FROM page
+    ) sub
+    WHERE prev IS NULL OR stable_version <> prev;
+
+CREATE VIEW current
+AS SELECT page, timestamp, stable_version, homepage
+    FROM (
+        SELECT *, rank() OVER (
+            PARTITION BY page ORDER BY timestamp DESC
+        )
+        FROM updates
+    ) sub
+    WHERE rank=1;
+
+CREATE TABLE IF NOT EXISTS curated
+    ( id text NOT NULL UNIQUE
+    , created timestamptz NOT NULL
+    , used int NOT NULL default 0
+    , lastused timestamptz NOT NULL
+    , lastupdated timestamptz NOT NULL
+    , title text NOT NULL default ''
+    );
+
+CREATE TABLE IF NOT EXISTS curated_pages
+    ( curated_id text NOT NULL
+    , page text NOT NULL
+    , UNIQUE (curated_id, page)
+    );
+`,
+	// 2: releases, page details, and revision info
+	`
+ALTER TABLE page
+    ADD COLUMN IF NOT EXISTS releases jsonb NOT NULL DEFAULT '[]'
+    , ADD COLUMN IF NOT EXISTS release_date timestamptz
+    , ADD COLUMN IF NOT EXISTS preview_version text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS preview_releases jsonb NOT NULL DEFAULT '[]'
+    , ADD COLUMN IF NOT EXISTS wikidata text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS developer text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS license text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS written_in text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS os text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS repository text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS etag text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS last_modified text NOT NULL DEFAULT ''
+    , ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 0
+    , ADD COLUMN IF NOT EXISTS revision_time timestamptz
+    , ADD COLUMN IF NOT EXISTS editor text NOT NULL DEFAULT '';
+
+DROP VIEW IF EXISTS current;
+DROP VIEW IF EXISTS updates;
+
+CREATE VIEW updates
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+    FROM (
+        SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+            , lag(stable_version) OVER w AS prev
+            , lag(preview_version) OVER w AS prev_preview
+            , lag(developer) OVER w AS prev_developer
+            , lag(license) OVER w AS prev_license
+            , lag(written_in) OVER w AS prev_written_in
+            , lag(os) OVER w AS prev_os
+            , lag(repository) OVER w AS prev_repository
+        FROM page
+        WINDOW w AS (PARTITION BY page ORDER BY timestamp)
+    ) sub
+    WHERE prev IS NULL OR stable_version <> prev OR preview_version <> prev_preview
+        OR developer <> prev_developer OR license <> prev_license
+        OR written_in <> prev_written_in OR os <> prev_os
+        OR repository <> prev_repository;
+
+CREATE VIEW current
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+    FROM (
+        SELECT *, rank() OVER (
+            PARTITION BY page ORDER BY timestamp DESC
+        )
+        FROM updates
+    ) sub
+    WHERE rank=1;
+`,
+	// 3: changes waiting for confirmation
+	`
+CREATE TABLE IF NOT EXISTS pending
+    ( page text NOT NULL UNIQUE
+    , since timestamptz NOT NULL
+    , seen int NOT NULL
+    , version jsonb NOT NULL
+    );
+`,
+	// 4: page search. pg_trgm is in postgresql-contrib, and creating it can
+	// need a superuser. Without it only prefix searches use an index.
+	`
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+    CREATE INDEX IF NOT EXISTS page_search ON page USING gin (lower(page) gin_trgm_ops);
+EXCEPTION WHEN undefined_file OR insufficient_privilege THEN
+    RAISE NOTICE 'no pg_trgm: %', SQLERRM;
+    CREATE INDEX IF NOT EXISTS page_search ON page (lower(page) text_pattern_ops);
+END
+$$;
+`,
+	// 5: parser version, to know when to reparse an unchanged revision
+	`
+ALTER TABLE page
+    ADD COLUMN IF NOT EXISTS parser text NOT NULL DEFAULT '';
+
+DROP VIEW IF EXISTS current;
+DROP VIEW IF EXISTS updates;
+
+CREATE VIEW updates
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+    FROM (
+        SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+            , lag(stable_version) OVER w AS prev
+            , lag(preview_version) OVER w AS prev_preview
+            , lag(developer) OVER w AS prev_developer
+            , lag(license) OVER w AS prev_license
+            , lag(written_in) OVER w AS prev_written_in
+            , lag(os) OVER w AS prev_os
+            , lag(repository) OVER w AS prev_repository
+        FROM page
+        WINDOW w AS (PARTITION BY page ORDER BY timestamp)
+    ) sub
+    WHERE prev IS NULL OR stable_version <> prev OR preview_version <> prev_preview
+        OR developer <> prev_developer OR license <> prev_license
+        OR written_in <> prev_written_in OR os <> prev_os
+        OR repository <> prev_repository;
+
+CREATE VIEW current
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+    FROM (
+        SELECT *, rank() OVER (
+            PARTITION BY page ORDER BY timestamp DESC
+        )
+        FROM updates
+    ) sub
+    WHERE rank=1;
+`,
+}
+
+// Migrate brings the schema up to date. It returns the version from before,
+// and the version now.
+func (p *Postgres) Migrate() (int, int, error) {
+	if _, err := p.pool.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version
+			( version int NOT NULL UNIQUE
+			, applied timestamptz NOT NULL
+			)
+	`); err != nil {
+		return 0, 0, err
+	}
+
+	from := -1
+	for {
+		v, applied, err := p.migrateNext()
+		if from == -1 {
+			from = v
+		}
+		if err != nil || !applied {
+			return from, v, err
+		}
+	}
+}
+
+// migrateNext applies the first missing migration, if any, and returns the
+// version from before that. The version table is locked, so several
+// processes can migrate at the same time.
+func (p *Postgres) migrateNext() (int, bool, error) {
+	tx, err := p.pool.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`LOCK TABLE schema_version IN EXCLUSIVE MODE`); err != nil {
+		return 0, false, err
+	}
+	var v int
+	if err := tx.QueryRow(`
+		SELECT coalesce(max(version), 0)
+		FROM schema_version
+	`).Scan(&v); err != nil {
+		return 0, false, err
+	}
+	if v > len(migrations) {
+		return v, false, fmt.Errorf("database schema version %d is newer than this program (%d)", v, len(migrations))
+	}
+	if v == len(migrations) {
+		return v, false, nil
+	}
+
+	if _, err := tx.Exec(migrations[v]); err != nil {
+		return v, false, fmt.Errorf("migration %d: %s", v+1, err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO schema_version (version, applied)
+		VALUES ($1, now())
+	`, v+1); err != nil {
+		return v, false, err
+	}
+	return v, true, tx.Commit()
+}
@@ -1,76 +1,104 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx"
 )
 
-const DBURL = "postgresql:///w"
+const (
+	DBURL = "postgresql:///w"
+	// DefaultConns is the connection pool size if none is given
+	DefaultConns = 10
+	// QueryTimeout limits every DB method
+	QueryTimeout = 10 * time.Second
+)
 
+// Postgres is safe for concurrent use.
 type Postgres struct {
-	conn *pgx.Conn
+	pool *pgx.ConnPool
 }
 
 var _ DB = &Postgres{}
 
-func NewPostgres(url string) (*Postgres, error) {
+// NewPostgres connects to url, with at most conns connections (DefaultConns
+// if 0).
+func NewPostgres(url string, conns int) (*Postgres, error) {
 	if url == "" {
 		url = DBURL
 	}
+	if conns == 0 {
+		conns = DefaultConns
+	}
+	if conns < 2 {
+		return nil, fmt.Errorf("need at least 2 connections, not %d", conns)
+	}
 	cc, err := pgx.ParseURI(url)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := pgx.Connect(cc)
+	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{
+		ConnConfig:     cc,
+		MaxConnections: conns,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	p := &Postgres{
-		conn: conn,
+		pool: pool,
 	}
 	return p, nil
 }
 
+// Close closes all connections
+func (p *Postgres) Close() {
+	p.pool.Close()
+}
+
 // recent updates to have something to show
-func (p *Postgres) Recent(n int) ([]Page, error) {
-	return p.queryCurrent(`
+func (p *Postgres) Recent(ctx context.Context, n int) ([]Page, error) {
+	return p.queryCurrent(ctx, `
 		ORDER BY timestamp DESC
 		LIMIT $1
     `, n)
 }
 
-func (p *Postgres) Last(page string) (*Page, error) {
-	row := p.conn.QueryRow(`
-		SELECT page, timestamp, stable_version, homepage
+func (p *Postgres) Last(ctx context.Context, page string) (*Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := p.pool.QueryRowEx(ctx, `
+		SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
 		FROM page
 		WHERE page=$1
 		ORDER BY timestamp DESC
 		LIMIT 1
-	`,
+	`, nil,
 		page,
 	)
-	res := Page{}
-	if err := row.Scan(&res.Page, &res.T, &res.StableVersion, &res.Homepage); err != nil {
+	res, err := scanPage(row)
+	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	res.T = res.T.UTC()
-	return &res, nil
+	return res, nil
 }
 
-func (p *Postgres) CurrentAll() ([]Page, error) {
-	return p.queryCurrent(`
+func (p *Postgres) CurrentAll(ctx context.Context) ([]Page, error) {
+	return p.queryCurrent(ctx, `
 		ORDER BY page
     `)
 }
 
-func (p *Postgres) Current(pages ...string) ([]Page, error) {
+func (p *Postgres) Current(ctx context.Context, pages ...string) ([]Page, error) {
 	if len(pages) == 0 {
 		return nil, nil
 	}
@@ -82,14 +110,14 @@ func (p *Postgres) Current(pages ...string) ([]Page, error) {
 		in = append(in, fmt.Sprintf("$%d", i+1))
 		args = append(args, p)
 	}
-	return p.queryCurrent(`
+	return p.queryCurrent(ctx, `
 		WHERE page IN (`+strings.Join(in, ",")+`)
 		ORDER BY timestamp DESC
     `, args...)
 }
 
 // History of a list of page. Newest first.
-func (p *Postgres) History(pages ...string) ([]Page, error) {
+func (p *Postgres) History(ctx context.Context, pages ...string) ([]Page, error) {
 	if len(pages) == 0 {
 		return nil, nil
 	}
@@ -101,58 +129,115 @@ func (p *Postgres) History(pages ...string) ([]Page, error) {
 		in = append(in, fmt.Sprintf("$%d", i+1))
 		args = append(args, p)
 	}
-	return p.queryUpdates(`
+	return p.queryUpdates(ctx, `
 		WHERE page IN (`+strings.Join(in, ",")+`)
 		ORDER BY timestamp DESC
     `, args...)
 }
 
-func (p *Postgres) queryCurrent(where string, args ...interface{}) ([]Page, error) {
-	return p.queryPages("current", where, args...)
+func (p *Postgres) queryCurrent(ctx context.Context, where string, args ...interface{}) ([]Page, error) {
+	return p.queryPages(ctx, "current", where, args...)
 }
 
-func (p *Postgres) queryUpdates(where string, args ...interface{}) ([]Page, error) {
-	return p.queryPages("updates", where, args...)
+func (p *Postgres) queryUpdates(ctx context.Context, where string, args ...interface{}) ([]Page, error) {
+	return p.queryPages(ctx, "updates", where, args...)
 }
 
-func (p *Postgres) queryPages(table, where string, args ...interface{}) ([]Page, error) {
+func (p *Postgres) queryPages(ctx context.Context, table, where string, args ...interface{}) ([]Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	var es []Page
-	rows, err := p.conn.Query(`
-		SELECT page, timestamp, stable_version, homepage
-		FROM `+table+where, args...)
+	rows, err := p.pool.QueryEx(ctx, `
+		SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+		FROM `+table+where, nil, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
-		var e Page
-		if err := rows.Scan(&e.Page, &e.T, &e.StableVersion, &e.Homepage); err != nil {
+		e, err := scanPage(rows)
+		if err != nil {
 			return nil, err
 		}
-		e.T = e.T.UTC()
-		es = append(es, e)
+		es = append(es, *e)
 	}
 	return es, rows.Err()
 }
 
-func (p *Postgres) Store(e Page) error {
-	_, err := p.conn.Exec(`
+type scanner interface {
+	Scan(...interface{}) error
+}
+
+// scanPage reads the columns from queryPages
+func scanPage(row scanner) (*Page, error) {
+	var (
+		e            Page
+		rels, prevws []byte
+		released     *time.Time
+		revised      *time.Time
+		err          error
+	)
+	if err := row.Scan(&e.Page, &e.T, &e.StableVersion, &rels, &released, &e.PreviewVersion, &prevws, &e.Homepage, &e.Wikidata, &e.Developer, &e.License, &e.WrittenIn, &e.OS, &e.Repository, &e.ETag, &e.LastModified, &e.Revision, &revised, &e.Editor, &e.Parser); err != nil {
+		return nil, err
+	}
+	e.T = e.T.UTC()
+	if released != nil {
+		e.ReleaseDate = released.UTC()
+	}
+	if revised != nil {
+		e.RevisionTime = revised.UTC()
+	}
+	if e.Releases, err = unmarshalReleases(rels); err != nil {
+		return nil, err
+	}
+	if e.PreviewReleases, err = unmarshalReleases(prevws); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (p *Postgres) Store(ctx context.Context, e Page) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rels, err := marshalReleases(e.Releases)
+	if err != nil {
+		return err
+	}
+	prevws, err := marshalReleases(e.PreviewReleases)
+	if err != nil {
+		return err
+	}
+	var released, revised *time.Time
+	if !e.ReleaseDate.IsZero() {
+		released = &e.ReleaseDate
+	}
+	if !e.RevisionTime.IsZero() {
+		revised = &e.RevisionTime
+	}
+	_, err = p.pool.ExecEx(ctx, `
 	INSERT INTO page
-		(page, timestamp, stable_version, homepage)
+		(page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser)
 	VALUES
-		($1, $2, $3, $4)
-`, e.Page, e.T, e.StableVersion, e.Homepage)
+		($1, $2, $3, $4::jsonb, $5, $6, $7::jsonb, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+`, nil, e.Page, e.T, e.StableVersion, rels, released, e.PreviewVersion, prevws, e.Homepage, e.Wikidata, e.Developer, e.License, e.WrittenIn, e.OS, e.Repository, e.ETag, e.LastModified, e.Revision, revised, e.Editor, e.Parser)
 	return err
 }
 
-func (p *Postgres) Known() ([]string, error) {
+func (p *Postgres) Known(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	var ps []string
-	rows, err := p.conn.Query(`
+	rows, err := p.pool.QueryEx(ctx, `
 		SELECT DISTINCT(page)
 		FROM updates
-		ORDER BY page`)
+		ORDER BY page`, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var p string
 		if err := rows.Scan(&p); err != nil {
@@ -163,15 +248,57 @@ func (p *Postgres) Known() ([]string, error) {
 	return ps, rows.Err()
 }
 
-func (p *Postgres) CreateCurated() (string, error) {
+// Search uses the page_search trigram index
+func (p *Postgres) Search(ctx context.Context, q string, limit int) ([]string, error) {
+	q = likeEscape(searchKey(q))
+	if q == "" || limit <= 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+	var ps []string
+	rows, err := p.pool.QueryEx(ctx, `
+		SELECT page
+		FROM page
+		WHERE lower(page) LIKE $1
+		GROUP BY page
+		ORDER BY lower(page) LIKE $2 DESC, page COLLATE "C"
+		LIMIT $3`, nil,
+		"%"+q+"%",
+		q+"%",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, rows.Err()
+}
+
+// likeEscape escapes the LIKE wildcards. Page names are full of underscores.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (p *Postgres) CreateCurated(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return "", err
 	}
 	cid := id.String()
-	_, err = p.conn.Exec(`
+	_, err = p.pool.ExecEx(ctx, `
 		INSERT INTO curated (id, created, lastused, lastupdated)
-		VALUES ($1, now(), now(), now())`,
+		VALUES ($1, now(), now(), now())`, nil,
 		cid,
 	)
 	return cid, err
@@ -181,11 +308,14 @@ func (p *Postgres) StoreCurated(cur Curated) error {
 	return nil
 }
 
-func (p *Postgres) LoadCurated(id string) (*Curated, error) {
-	row := p.conn.QueryRow(`
+func (p *Postgres) LoadCurated(ctx context.Context, id string) (*Curated, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := p.pool.QueryRowEx(ctx, `
 		SELECT created, lastused, lastupdated, title
 		FROM curated
-		WHERE id=$1`,
+		WHERE id=$1`, nil,
 		id,
 	)
 	cur := Curated{}
@@ -195,7 +325,7 @@ func (p *Postgres) LoadCurated(id string) (*Curated, error) {
 		}
 		return nil, err
 	}
-	pg, err := p.curatedPages(id)
+	pg, err := p.curatedPages(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -203,18 +333,19 @@ func (p *Postgres) LoadCurated(id string) (*Curated, error) {
 	return &cur, nil
 }
 
-func (p *Postgres) curatedPages(id string) ([]string, error) {
+func (p *Postgres) curatedPages(ctx context.Context, id string) ([]string, error) {
 	var ps []string
-	rows, err := p.conn.Query(`
+	rows, err := p.pool.QueryEx(ctx, `
 		SELECT page
 		FROM curated_pages
 		WHERE curated_id=$1
-		ORDER BY page`,
+		ORDER BY page`, nil,
 		id,
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var p string
 		if err := rows.Scan(&p); err != nil {
@@ -226,44 +357,177 @@ func (p *Postgres) curatedPages(id string) ([]string, error) {
 }
 
 // pages must be unique
-func (p *Postgres) CuratedSetPages(id string, pages []string) error {
-	tx, err := p.conn.Begin()
+func (p *Postgres) CuratedSetPages(ctx context.Context, id string, pages []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := p.pool.BeginEx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec(`DELETE FROM curated_pages WHERE curated_id=$1`, id); err != nil {
+	// Updating the curated row first locks it, so concurrent calls for the
+	// same id wait for each other.
+	res, err := tx.ExecEx(ctx, `UPDATE curated SET lastupdated=now() WHERE id=$1`, nil, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrCuratedNotFound
+	}
+	if _, err := tx.ExecEx(ctx, `DELETE FROM curated_pages WHERE curated_id=$1`, nil, id); err != nil {
 		return err
 	}
 	for _, p := range pages {
-		if _, err := tx.Exec(`INSERT INTO curated_pages (curated_id, page) VALUES ($1, $2)`, id, p); err != nil {
+		if _, err := tx.ExecEx(ctx, `INSERT INTO curated_pages (curated_id, page) VALUES ($1, $2)`, nil, id, p); err != nil {
 			return err
 		}
 	}
-	res, err := p.conn.Exec(`UPDATE curated SET lastupdated=now() WHERE id=$1`, id)
+	return tx.CommitEx(ctx)
+}
+
+func (p *Postgres) CuratedSetUsed(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	res, err := p.pool.ExecEx(ctx, `UPDATE curated SET lastused=now(), used=used+1 WHERE id=$1`, nil, id)
 	if err != nil {
 		return err
 	}
 	if res.RowsAffected() == 0 {
-		tx.Rollback()
 		return ErrCuratedNotFound
 	}
-	return tx.Commit()
+	return nil
 }
 
-func (p *Postgres) CuratedSetUsed(id string) error {
-	res, err := p.conn.Exec(`UPDATE curated SET lastused=now(), used=used+1 WHERE id=$1`, id)
+func (p *Postgres) CuratedSetTitle(ctx context.Context, id, title string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	res, err := p.pool.ExecEx(ctx, `UPDATE curated SET title=$2, lastupdated=now() WHERE id=$1`, nil, id, title)
+	if err != nil {
+		return err
+	}
 	if res.RowsAffected() == 0 {
 		return ErrCuratedNotFound
 	}
-	return err
+	return nil
 }
 
-func (p *Postgres) CuratedSetTitle(id, title string) error {
-	res, err := p.conn.Exec(`UPDATE curated SET title=$2, lastupdated=now() WHERE id=$1`, id, title)
-	if res.RowsAffected() == 0 {
-		return ErrCuratedNotFound
+// MergePage renames all rows of page from to page to, including the curated
+// lists which have it. Used to clean up duplicate spellings of a page, see
+// Canonical.
+func (p *Postgres) MergePage(ctx context.Context, from, to string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := p.pool.BeginEx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecEx(ctx, `UPDATE page SET page=$2 WHERE page=$1`, nil, from, to); err != nil {
+		return err
+	}
+	if _, err := tx.ExecEx(ctx, `
+		DELETE FROM curated_pages c
+		WHERE page=$1
+		AND EXISTS (
+			SELECT 1 FROM curated_pages
+			WHERE curated_id=c.curated_id AND page=$2
+		)`, nil, from, to); err != nil {
+		return err
+	}
+	if _, err := tx.ExecEx(ctx, `UPDATE curated_pages SET page=$2 WHERE page=$1`, nil, from, to); err != nil {
+		return err
+	}
+	if _, err := tx.ExecEx(ctx, `DELETE FROM pending WHERE page=$1`, nil, from); err != nil {
+		return err
+	}
+	return tx.CommitEx(ctx)
+}
+
+func (p *Postgres) LoadPending(ctx context.Context, page string) (*Pending, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := p.pool.QueryRowEx(ctx, `
+		SELECT since, seen, version
+		FROM pending
+		WHERE page=$1`, nil,
+		page,
+	)
+	res, err := scanPending(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (p *Postgres) SetPending(ctx context.Context, pend Pending) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	v, err := json.Marshal(pend.Page)
+	if err != nil {
+		return err
 	}
+	_, err = p.pool.ExecEx(ctx, `
+		INSERT INTO pending
+			(page, since, seen, version)
+		VALUES
+			($1, $2, $3, $4::jsonb)
+		ON CONFLICT (page) DO UPDATE
+			SET since=EXCLUDED.since, seen=EXCLUDED.seen, version=EXCLUDED.version`, nil,
+		pend.Page.Page, pend.Since, pend.Seen, string(v),
+	)
 	return err
 }
+
+func (p *Postgres) DeletePending(ctx context.Context, page string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := p.pool.ExecEx(ctx, `DELETE FROM pending WHERE page=$1`, nil, page)
+	return err
+}
+
+func (p *Postgres) PendingAll(ctx context.Context) ([]Pending, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := p.pool.QueryEx(ctx, `
+		SELECT since, seen, version
+		FROM pending
+		ORDER BY since, page`, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ps []Pending
+	for rows.Next() {
+		pend, err := scanPending(rows)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, *pend)
+	}
+	return ps, rows.Err()
+}
+
+func scanPending(row scanner) (*Pending, error) {
+	var (
+		pend Pending
+		v    []byte
+	)
+	if err := row.Scan(&pend.Since, &pend.Seen, &v); err != nil {
+		return nil, err
+	}
+	pend.Since = pend.Since.UTC()
+	if err := json.Unmarshal(v, &pend.Page); err != nil {
+		return nil, err
+	}
+	return &pend, nil
+}
@@ -3,24 +3,53 @@
 package core
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 )
 
-var tables = []string{"page", "curated", "curated_pages"}
+var tables = []string{"page", "curated", "curated_pages", "pending"}
 
 func initdb(t *testing.T) DB {
-	p, err := NewPostgres("postgresql:///verssion")
+	p, err := NewPostgres("postgresql:///verssion", 0)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if _, _, err := p.Migrate(); err != nil {
+		t.Fatal(err)
+	}
 	for _, table := range tables {
-		if _, err := p.conn.Exec("DELETE FROM " + table); err != nil {
+		if _, err := p.pool.Exec("DELETE FROM " + table); err != nil {
 			t.Fatal(err)
 		}
 	}
 	return p
 }
 
+func TestPostgresMigrate(t *testing.T) {
+	p := initdb(t).(*Postgres)
+
+	// initdb already migrated
+	from, to, err := p.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := from, len(migrations); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := to, len(migrations); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// all migrations are safe to run on the current schema
+	for i, m := range migrations {
+		if _, err := p.pool.Exec(m); err != nil {
+			t.Errorf("migration %d: %s", i+1, err)
+		}
+	}
+}
+
 func TestPostgresDB(t *testing.T) {
 	p := initdb(t)
 	InterfaceTestDB(t, p)
@@ -31,3 +60,60 @@ func TestPostgresCurated(t *testing.T) {
 
 	InterfaceTestCurated(t, p)
 }
+
+func TestPostgresSearch(t *testing.T) {
+	p := initdb(t)
+
+	InterfaceTestSearch(t, p)
+}
+
+func TestPostgresPending(t *testing.T) {
+	p := initdb(t)
+
+	InterfaceTestPending(t, p)
+}
+
+func TestPostgresConcurrent(t *testing.T) {
+	p := initdb(t)
+
+	InterfaceTestConcurrent(t, p)
+}
+
+func TestPostgresMergePage(t *testing.T) {
+	ctx := context.Background()
+	p := initdb(t).(*Postgres)
+
+	for _, page := range []Page{
+		{Page: "Foo_bar", T: time.Now().Add(-time.Hour), StableVersion: "1.0"},
+		{Page: "foo_bar", T: time.Now(), StableVersion: "1.1"},
+	} {
+		if err := p.Store(ctx, page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id, err := p.CreateCurated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CuratedSetPages(ctx, id, []string{"Foo_bar", "foo_bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.MergePage(ctx, "foo_bar", "Foo_bar"); err != nil {
+		t.Fatal(err)
+	}
+	known, err := p.Known(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := known, []string{"Foo_bar"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	cur, err := p.LoadCurated(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := cur.Pages, []string{"Foo_bar"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
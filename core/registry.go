@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Package registries and code hosts, for projects which don't have a
+// wikipedia page.
+
+const (
+	GitHubAPI = "https://api.github.com"
+	PyPIAPI   = "https://pypi.org"
+	NPMAPI    = "https://registry.npmjs.org"
+	CratesAPI = "https://crates.io"
+)
+
+// getJSON loads and decodes a JSON document. 404s are ErrNotFound.
+func getJSON(ctx context.Context, page, u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/json")
+	r, err := do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	switch code := r.StatusCode; code {
+	case 200:
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+			return fmt.Errorf("%q: %s", page, err)
+		}
+		return nil
+	case 404:
+		return ErrNotFound{Page: page}
+	default:
+		return fmt.Errorf("%q: registry error (status: %d)", page, code)
+	}
+}
+
+// registryPage fills in the version fields
+func registryPage(page, stable string, stableT time.Time, preview string, previewT time.Time, homepage string) (Page, error) {
+	p := Page{
+		Page:           page,
+		T:              time.Now().UTC(),
+		StableVersion:  versionDate(stable, stableT),
+		PreviewVersion: versionDate(preview, previewT),
+		Homepage:       stripScheme(homepage),
+	}
+	if p.StableVersion == "" {
+		return p, fmt.Errorf("%q: no version found", page)
+	}
+	p.Releases = ParseReleases(p.StableVersion)
+	p.ReleaseDate = LatestDate(p.Releases)
+	p.PreviewReleases = ParseReleases(p.PreviewVersion)
+	return p, nil
+}
+
+// versionDate formats as "1.2.3 / 22 September 2017"
+func versionDate(v string, t time.Time) string {
+	if v == "" || t.IsZero() {
+		return v
+	}
+	return v + " / " + t.UTC().Format("2 January 2006")
+}
+
+// GitHub reads the releases of a repository. Pages look like
+// "github:owner/repo".
+type GitHub struct {
+	API string
+}
+
+func (g GitHub) Fetch(ctx context.Context, page string) (Page, error) {
+	_, repo := Namespace(page)
+	if strings.Count(repo, "/") != 1 {
+		return Page{Page: page}, ErrNotFound{Page: page}
+	}
+	var rels []struct {
+		Tag         string    `json:"tag_name"`
+		Draft       bool      `json:"draft"`
+		Prerelease  bool      `json:"prerelease"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := getJSON(ctx, page, g.API+"/repos/"+repo+"/releases?per_page=30", &rels); err != nil {
+		return Page{Page: page}, err
+	}
+	// newest first
+	var (
+		stable, preview   string
+		stableT, previewT time.Time
+	)
+	for _, r := range rels {
+		switch {
+		case r.Draft:
+		case r.Prerelease:
+			if preview == "" && stable == "" {
+				preview, previewT = r.Tag, r.PublishedAt
+			}
+		case stable == "":
+			stable, stableT = r.Tag, r.PublishedAt
+		}
+	}
+	p, err := registryPage(page, stable, stableT, preview, previewT, "github.com/"+repo)
+	p.Repository = "github.com/" + repo
+	return p, err
+}
+
+// PyPI reads the Python package index. Pages look like "pypi:django".
+type PyPI struct {
+	API string
+}
+
+func (py PyPI) Fetch(ctx context.Context, page string) (Page, error) {
+	_, name := Namespace(page)
+	var res struct {
+		Info struct {
+			Version     string            `json:"version"`
+			HomePage    string            `json:"home_page"`
+			License     string            `json:"license"`
+			Author      string            `json:"author"`
+			ProjectURLs map[string]string `json:"project_urls"`
+		} `json:"info"`
+		Releases map[string][]struct {
+			UploadTime string `json:"upload_time_iso_8601"`
+		} `json:"releases"`
+	}
+	if err := getJSON(ctx, page, py.API+"/pypi/"+url.PathEscape(name)+"/json", &res); err != nil {
+		return Page{Page: page}, err
+	}
+	uploaded := func(v string) time.Time {
+		for _, f := range res.Releases[v] {
+			if t, err := time.Parse(time.RFC3339, f.UploadTime); err == nil {
+				return t
+			}
+		}
+		return time.Time{}
+	}
+
+	// info.version is the newest stable version.
+	stable, preview := res.Info.Version, ""
+	for v := range res.Releases {
+		if len(res.Releases[v]) == 0 || !pep440Pre(v) {
+			continue
+		}
+		if CompareVersions(v, stable) > 0 && (preview == "" || CompareVersions(v, preview) > 0) {
+			preview = v
+		}
+	}
+	home := res.Info.HomePage
+	if h, ok := res.Info.ProjectURLs["Homepage"]; ok && home == "" {
+		home = h
+	}
+	p, err := registryPage(page, stable, uploaded(stable), preview, uploaded(preview), home)
+	p.License = res.Info.License
+	p.Developer = res.Info.Author
+	p.Repository = stripScheme(res.Info.ProjectURLs["Source"])
+	p.WrittenIn = "Python"
+	return p, err
+}
+
+// pep440Pre is true for pre-releases ("2.0b1", "2.0rc1", "2.0.dev3"), but not
+// for post-releases ("1.2.post1").
+func pep440Pre(v string) bool {
+	for _, p := range versionParts(v) {
+		if _, ok := preReleases[strings.ToLower(p)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NPM reads the npm registry. Pages look like "npm:react" or
+// "npm:@angular/core".
+type NPM struct {
+	API string
+}
+
+func (n NPM) Fetch(ctx context.Context, page string) (Page, error) {
+	_, name := Namespace(page)
+	var res struct {
+		DistTags   map[string]string    `json:"dist-tags"`
+		Time       map[string]time.Time `json:"time"`
+		Homepage   string               `json:"homepage"`
+		License    json.RawMessage      `json:"license"`
+		Repository json.RawMessage      `json:"repository"`
+	}
+	// scoped packages keep their "@", but escape the "/"
+	if err := getJSON(ctx, page, n.API+"/"+strings.Replace(name, "/", "%2F", 1), &res); err != nil {
+		return Page{Page: page}, err
+	}
+	stable, preview := res.DistTags["latest"], ""
+	for _, tag := range []string{"next", "beta", "rc", "canary"} {
+		if v, ok := res.DistTags[tag]; ok && v != stable && CompareVersions(v, stable) > 0 {
+			preview = v
+			break
+		}
+	}
+	p, err := registryPage(page, stable, res.Time[stable], preview, res.Time[preview], res.Homepage)
+	p.License = npmField(res.License, "type")
+	p.Repository = stripScheme(strings.TrimSuffix(strings.TrimPrefix(npmField(res.Repository, "url"), "git+"), ".git"))
+	p.WrittenIn = "JavaScript"
+	return p, err
+}
+
+// npmField reads a field which is either a plain string, or an object with
+// the value in key. Old packages use `"license": {"type": "MIT"}`.
+func npmField(raw json.RawMessage, key string) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var m map[string]interface{}
+	if json.Unmarshal(raw, &m) == nil {
+		if v, ok := m[key].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// Crates reads the Rust crate registry. Pages look like "crates:serde".
+type Crates struct {
+	API string
+}
+
+func (c Crates) Fetch(ctx context.Context, page string) (Page, error) {
+	_, name := Namespace(page)
+	var res struct {
+		Crate struct {
+			MaxStable  string `json:"max_stable_version"`
+			Max        string `json:"max_version"`
+			Homepage   string `json:"homepage"`
+			Repository string `json:"repository"`
+		} `json:"crate"`
+		Versions []struct {
+			Num       string    `json:"num"`
+			CreatedAt time.Time `json:"created_at"`
+			License   string    `json:"license"`
+		} `json:"versions"`
+	}
+	if err := getJSON(ctx, page, c.API+"/api/v1/crates/"+url.PathEscape(name), &res); err != nil {
+		return Page{Page: page}, err
+	}
+	created := func(v string) time.Time {
+		for _, r := range res.Versions {
+			if r.Num == v {
+				return r.CreatedAt
+			}
+		}
+		return time.Time{}
+	}
+	license := func(v string) string {
+		for _, r := range res.Versions {
+			if r.Num == v {
+				return r.License
+			}
+		}
+		return ""
+	}
+	stable, preview := res.Crate.MaxStable, ""
+	if m := res.Crate.Max; m != stable {
+		preview = m
+	}
+	home := res.Crate.Homepage
+	if home == "" {
+		home = res.Crate.Repository
+	}
+	p, err := registryPage(page, stable, created(stable), preview, created(preview), home)
+	p.License = license(stable)
+	p.Repository = stripScheme(res.Crate.Repository)
+	p.WrittenIn = "Rust"
+	return p, err
+}
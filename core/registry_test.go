@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// registryServer serves fixed JSON documents by path
+func registryServer(t *testing.T, docs map[string]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(doc))
+	}))
+}
+
+func TestPEP440Pre(t *testing.T) {
+	for v, want := range map[string]bool{
+		"2.0":          false,
+		"2.0a1":        true,
+		"2.0b1":        true,
+		"2.0rc1":       true,
+		"2.0.dev3":     true,
+		"2.0RC1":       true,
+		"1.2.post1":    false,
+		"1.2.post1.1":  false,
+		"1.2+ubuntu.1": false,
+	} {
+		if have := pep440Pre(v); have != want {
+			t.Errorf("%q: have %t, want %t", v, have, want)
+		}
+	}
+}
+
+func TestRegistries(t *testing.T) {
+	ctx := context.Background()
+	s := registryServer(t, map[string]string{
+		"/repos/golang/go/releases": `[
+			{"tag_name": "go1.10beta1", "prerelease": true, "published_at": "2017-12-07T20:00:00Z"},
+			{"tag_name": "go1.9.3", "draft": true, "published_at": "2018-01-22T20:00:00Z"},
+			{"tag_name": "go1.9.2", "published_at": "2017-10-25T20:00:00Z"},
+			{"tag_name": "go1.9.1", "published_at": "2017-10-04T20:00:00Z"}
+		]`,
+		"/pypi/django/json": `{
+			"info": {"version": "1.11.6", "home_page": "https://www.djangoproject.com/", "license": "BSD", "author": "Django Software Foundation"},
+			"releases": {
+				"1.11.5": [{"upload_time_iso_8601": "2017-09-05T12:00:00.000000Z"}],
+				"1.11.6": [{"upload_time_iso_8601": "2017-10-05T12:00:00.000000Z"}],
+				"1.11.6.post1": [{"upload_time_iso_8601": "2017-10-06T12:00:00.000000Z"}],
+				"2.0b1": [{"upload_time_iso_8601": "2017-10-17T12:00:00.000000Z"}],
+				"2.0a1": [{"upload_time_iso_8601": "2017-09-22T12:00:00.000000Z"}],
+				"1.0b1": [{"upload_time_iso_8601": "2008-08-15T12:00:00.000000Z"}]
+			}
+		}`,
+		"/pypi/requests/json": `{
+			"info": {"version": "2.0.0", "home_page": "http://python-requests.org"},
+			"releases": {
+				"2.0.0": [{"upload_time_iso_8601": "2013-09-24T12:00:00.000000Z"}],
+				"2.0.0rc1": [{"upload_time_iso_8601": "2013-09-20T12:00:00.000000Z"}]
+			}
+		}`,
+		"/@angular%2Fcore": `{
+			"dist-tags": {"latest": "5.0.0", "next": "5.1.0-beta.0"},
+			"time": {"5.0.0": "2017-11-01T18:00:00.000Z", "5.1.0-beta.0": "2017-11-08T18:00:00.000Z"},
+			"homepage": "https://github.com/angular/angular#readme",
+			"license": "MIT",
+			"repository": {"type": "git", "url": "git+https://github.com/angular/angular.git"}
+		}`,
+		"/left-pad": `{
+			"dist-tags": {"latest": "1.3.0", "rc": "1.3.0-rc.1"},
+			"time": {"1.3.0": "2018-04-09T18:00:00.000Z", "1.3.0-rc.1": "2018-04-01T18:00:00.000Z"},
+			"license": "WTFPL"
+		}`,
+		"/api/v1/crates/serde": `{
+			"crate": {"max_stable_version": "1.0.18", "max_version": "1.0.18", "repository": "https://github.com/serde-rs/serde"},
+			"versions": [
+				{"num": "1.0.18", "created_at": "2017-11-06T05:00:00Z", "license": "MIT/Apache-2.0"},
+				{"num": "1.0.17", "created_at": "2017-11-01T05:00:00Z"}
+			]
+		}`,
+	})
+	defer s.Close()
+
+	src := Sources{
+		"github": GitHub{API: s.URL},
+		"pypi":   PyPI{API: s.URL},
+		"npm":    NPM{API: s.URL},
+		"crates": Crates{API: s.URL},
+	}
+
+	type cas struct {
+		Page       string
+		Stable     string
+		Preview    string
+		Homepage   string
+		License    string
+		Repository string
+	}
+	for _, c := range []cas{
+		{
+			Page:       "github:golang/go",
+			Stable:     "go1.9.2 / 25 October 2017",
+			Preview:    "go1.10beta1 / 7 December 2017",
+			Homepage:   "github.com/golang/go",
+			Repository: "github.com/golang/go",
+		},
+		{
+			Page:     "pypi:django",
+			Stable:   "1.11.6 / 5 October 2017",
+			Preview:  "2.0b1 / 17 October 2017",
+			Homepage: "www.djangoproject.com",
+			License:  "BSD",
+		},
+		{
+			// old release candidate
+			Page:     "pypi:requests",
+			Stable:   "2.0.0 / 24 September 2013",
+			Homepage: "python-requests.org",
+		},
+		{
+			Page:       "npm:@angular/core",
+			Stable:     "5.0.0 / 1 November 2017",
+			Preview:    "5.1.0-beta.0 / 8 November 2017",
+			Homepage:   "github.com/angular/angular#readme",
+			License:    "MIT",
+			Repository: "github.com/angular/angular",
+		},
+		{
+			// old release candidate
+			Page:    "npm:left-pad",
+			Stable:  "1.3.0 / 9 April 2018",
+			License: "WTFPL",
+		},
+		{
+			Page:       "crates:serde",
+			Stable:     "1.0.18 / 6 November 2017",
+			Homepage:   "github.com/serde-rs/serde",
+			License:    "MIT/Apache-2.0",
+			Repository: "github.com/serde-rs/serde",
+		},
+	} {
+		p, err := src.Fetch(ctx, c.Page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.Page, c.Page; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.StableVersion, c.Stable; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.PreviewVersion, c.Preview; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Homepage, c.Homepage; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.License, c.License; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Repository, c.Repository; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if p.ReleaseDate.IsZero() {
+			t.Errorf("%s: no release date", c.Page)
+		}
+	}
+
+	for _, page := range []string{
+		"github:nosuch/repo",
+		"github:norepo",
+		"pypi:nosuch",
+		"npm:nosuch",
+		"crates:nosuch",
+	} {
+		_, err := src.Fetch(ctx, page)
+		if have, want := err, (ErrNotFound{Page: page}); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
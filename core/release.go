@@ -0,0 +1,286 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Release is a single version as listed in an infobox
+type Release struct {
+	Label    string    `json:"label,omitempty"` // "Standard", "ESR", ...
+	Version  string    `json:"version"`
+	Date     time.Time `json:"date"` // zero if unknown
+	Codename string    `json:"codename,omitempty"`
+}
+
+func (r Release) String() string {
+	s := r.Version
+	if r.Label != "" {
+		s = r.Label + " " + s
+	}
+	if r.Codename != "" {
+		s += " (" + r.Codename + ")"
+	}
+	return s
+}
+
+var dateLayouts = []struct {
+	words  int
+	layout string
+}{
+	{3, "2 January 2006"},
+	{3, "January 2, 2006"},
+	{1, "2006-01-02"},
+	{2, "January 2006"},
+}
+
+// ParseReleases splits an infobox version string, such as "3.6.3 / 3 October
+// 2017\n2.7.14 / 16 September 2017", into separate releases. One release per
+// line.
+func ParseReleases(s string) []Release {
+	var rs []Release
+	for _, l := range strings.Split(s, "\n") {
+		if r, ok := parseRelease(l); ok {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+func parseRelease(l string) (Release, bool) {
+	var (
+		r         Release
+		name      = l
+		date      = ""
+		rest      []string
+		foundVers = false
+	)
+	if i := strings.Index(l, " / "); i >= 0 {
+		name, date = l[:i], l[i+3:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return r, false
+	}
+	if i := strings.LastIndex(name, "("); i >= 0 && strings.HasSuffix(name, ")") {
+		r.Codename = strings.TrimSpace(name[i+1 : len(name)-1])
+		name = strings.TrimSpace(name[:i])
+	}
+	var label []string
+	for _, w := range strings.Fields(name) {
+		switch {
+		case foundVers:
+			rest = append(rest, w)
+		case isVersion(w):
+			r.Version = w
+			foundVers = true
+		default:
+			label = append(label, w)
+		}
+	}
+	if !foundVers {
+		// no idea, keep it as a whole
+		r.Version = name
+		label = nil
+	}
+	r.Label = strings.Join(label, " ")
+	if len(rest) > 0 && r.Codename == "" {
+		r.Codename = strings.Join(rest, " ")
+	}
+	if date == "" && r.Codename != "" {
+		// German wikipedia: "10.0 (5. Oktober 2017)"
+		if t := parseDate(r.Codename); !t.IsZero() {
+			date, r.Codename = r.Codename, ""
+		}
+	}
+	r.Date = parseDate(date)
+	return r, true
+}
+
+// isVersion guesses whether a word is a version number: "10.0", "v2", "58.0a1"
+func isVersion(w string) bool {
+	w = strings.TrimPrefix(w, "v")
+	return len(w) > 0 && unicode.IsDigit(rune(w[0]))
+}
+
+// parseDate finds a date at the start of s. Wikipedia has a few formats, and
+// there can be some text after the date.
+func parseDate(s string) time.Time {
+	ws := strings.Fields(months.Replace(strings.Replace(s, ";", " ", -1) + " "))
+	if len(ws) > 0 && isVersion(ws[0]) {
+		// "5. Oktober 2017", "1er octobre 2017"
+		ws[0] = strings.TrimSuffix(strings.TrimSuffix(ws[0], "."), "er")
+	}
+	for _, l := range dateLayouts {
+		if len(ws) < l.words {
+			continue
+		}
+		if t, err := time.Parse(l.layout, strings.Join(ws[:l.words], " ")); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// LatestDate is the most recent release date, or the zero time.
+func LatestDate(rs []Release) time.Time {
+	var t time.Time
+	for _, r := range rs {
+		if r.Date.After(t) {
+			t = r.Date
+		}
+	}
+	return t
+}
+
+// CompareVersions compares two version strings part by part. Numbers compare
+// as numbers, and missing numbers are 0: "2.0" is "2.0.0". A pre-release
+// ("2.0rc1", "58.0a1") is before its release, other letters are after it
+// ("1.0.2k", "7.6p1", "1.2.post1"). Returns -1, 0, or 1.
+func CompareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if c := comparePart(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// preReleases are the pre-release tags, in order
+var preReleases = map[string]int{
+	"dev":     0,
+	"a":       1,
+	"alpha":   1,
+	"b":       2,
+	"beta":    2,
+	"c":       3,
+	"pre":     3,
+	"preview": 3,
+	"rc":      3,
+}
+
+// comparePart compares a single part of a version. "" is the end of the
+// version, which is 0 next to a number. Order: pre-release tags, the end,
+// other letters, numbers.
+func comparePart(x, y string) int {
+	if x == "" && isNumber(y) {
+		x = "0"
+	}
+	if y == "" && isNumber(x) {
+		y = "0"
+	}
+	xr, yr := partRank(x), partRank(y)
+	switch {
+	case xr < yr:
+		return -1
+	case xr > yr:
+		return 1
+	}
+	if xn, ok := atoi(x); ok {
+		if yn, ok := atoi(y); ok {
+			return compareInt(xn, yn)
+		}
+	}
+	xp, xok := preReleases[strings.ToLower(x)]
+	yp, yok := preReleases[strings.ToLower(y)]
+	if xok && yok && xp != yp {
+		return compareInt(xp, yp)
+	}
+	return strings.Compare(x, y)
+}
+
+func partRank(p string) int {
+	switch _, pre := preReleases[strings.ToLower(p)]; {
+	case pre:
+		return 0
+	case p == "":
+		return 1
+	case isNumber(p):
+		return 3
+	}
+	return 2
+}
+
+func isNumber(p string) bool {
+	return p != "" && unicode.IsDigit(rune(p[0]))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// "58.0a1" -> "58", "0", "a", "1"
+func versionParts(v string) []string {
+	var (
+		parts []string
+		cur   []rune
+		digit bool
+	)
+	for _, r := range strings.TrimPrefix(v, "v") {
+		isDigit := unicode.IsDigit(r)
+		if !unicode.IsLetter(r) && !isDigit {
+			if len(cur) > 0 {
+				parts = append(parts, string(cur))
+			}
+			cur = nil
+			continue
+		}
+		if len(cur) > 0 && isDigit != digit {
+			parts = append(parts, string(cur))
+			cur = nil
+		}
+		cur = append(cur, r)
+		digit = isDigit
+	}
+	if len(cur) > 0 {
+		parts = append(parts, string(cur))
+	}
+	return parts
+}
+
+func atoi(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func marshalReleases(rs []Release) (string, error) {
+	if rs == nil {
+		rs = []Release{}
+	}
+	b, err := json.Marshal(rs)
+	return string(b), err
+}
+
+func unmarshalReleases(b []byte) ([]Release, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var rs []Release
+	if err := json.Unmarshal(b, &rs); err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, nil
+	}
+	for i := range rs {
+		rs[i].Date = rs[i].Date.UTC()
+	}
+	return rs, nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseReleases(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	for version, want := range map[string][]Release{
+		"2.14.2 / 22 September 2017": {
+			{Version: "2.14.2", Date: day(2017, 9, 22)},
+		},
+		"9.2 (Stretch)": {
+			{Version: "9.2", Codename: "Stretch"},
+		},
+		"3.6.3 / 3 October 2017\n2.7.14 / 16 September 2017": {
+			{Version: "3.6.3", Date: day(2017, 10, 3)},
+			{Version: "2.7.14", Date: day(2017, 9, 16)},
+		},
+		"Standard 56.0.2 / 26 October 2017\nESR 52.4.1 / 9 October 2017": {
+			{Label: "Standard", Version: "56.0.2", Date: day(2017, 10, 26)},
+			{Label: "ESR", Version: "52.4.1", Date: day(2017, 10, 9)},
+		},
+		"Beta & Developer Edition 57.0beta / September 26, 2017 semiweekly release": {
+			{Label: "Beta & Developer Edition", Version: "57.0beta", Date: day(2017, 9, 26)},
+		},
+		"8.2.1 / July 22, 2017": {
+			{Version: "8.2.1", Date: day(2017, 7, 22)},
+		},
+		"1.2.3 / 2017-07-22": {
+			{Version: "1.2.3", Date: day(2017, 7, 22)},
+		},
+		"4.64": {
+			{Version: "4.64"},
+		},
+		"10.0 (5. Oktober 2017)": {
+			{Version: "10.0", Date: day(2017, 10, 5)},
+		},
+		"10.0 / 5 octobre 2017": {
+			{Version: "10.0", Date: day(2017, 10, 5)},
+		},
+		"10.0 / 1er octobre 2017": {
+			{Version: "10.0", Date: day(2017, 10, 1)},
+		},
+		"my version": {
+			{Version: "my version"},
+		},
+		"": nil,
+	} {
+		if have := ParseReleases(version); !reflect.DeepEqual(have, want) {
+			t.Errorf("%q: have %#v, want %#v", version, have, want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	type cas struct {
+		A, B string
+		Want int
+	}
+	for _, c := range []cas{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.0", "1.0", 1},
+		{"1.9", "1.10", -1},
+		{"1.0", "1.0.1", -1},
+		{"v2.1", "2.1", 0},
+		{"58.0a1", "58.0b1", -1},
+		{"57.0beta", "57.0", -1},
+		{"2.0rc1", "2.0", -1},
+		{"2.0", "2.0rc1", 1},
+		{"2.0a1", "2.0b1", -1},
+		{"2.0b2", "2.0rc1", -1},
+		{"2.0rc2", "2.0rc10", -1},
+		{"1.9", "2.0a1", -1},
+		{"2.0a1", "2.0.1", -1},
+		{"1.2.post1", "1.2", 1},
+		{"1.2.post1", "1.2.1", -1},
+		{"5.1.0-beta.0", "5.1.0", -1},
+		{"2.0.0", "2.0", 0},
+		{"2.0", "2.0.0", 0},
+		{"2.0.1", "2.0", 1},
+		{"1.0.2k", "1.0.2", 1},
+		{"1.0.2j", "1.0.2k", -1},
+		{"1.0.2k", "1.0.3", -1},
+		{"7.6p1", "7.6", 1},
+		{"7.6p1", "7.7", -1},
+		{"2.0.dev1", "2.0a1", -1},
+		{"2.0b1", "2.0c1", -1},
+		{"2.0BETA1", "2.0rc1", -1},
+		{"2.0.0rc1", "2.0", -1},
+	} {
+		if have, want := CompareVersions(c.A, c.B), c.Want; have != want {
+			t.Errorf("%q vs %q: have %d, want %d", c.A, c.B, have, want)
+		}
+	}
+}
+
+func TestLatestDate(t *testing.T) {
+	rs := ParseReleases("3.6.3 / 3 October 2017\n2.7.14 / 16 September 2017\n1.0")
+	if have, want := LatestDate(rs), time.Date(2017, 10, 3, 0, 0, 0, 0, time.UTC); !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have := LatestDate(nil); !have.IsZero() {
+		t.Errorf("have %v, want zero", have)
+	}
+}
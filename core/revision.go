@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WikiAPIURL is the api.php of a wikipedia
+func WikiAPIURL(lang string) string {
+	return "https://" + lang + ".wikipedia.org/w/api.php"
+}
+
+// RevisionURL links to a revision of a wikipedia page. Empty if there is no
+// revision, such as for pages in a namespace.
+func RevisionURL(page string, rev int64) string {
+	if rev == 0 {
+		return ""
+	}
+	return indexURL(page, url.Values{"oldid": {strconv.FormatInt(rev, 10)}})
+}
+
+// DiffURL links to the changes from revision from to revision rev. Without
+// from it's only the changes made in rev.
+func DiffURL(page string, rev, from int64) string {
+	if rev == 0 {
+		return ""
+	}
+	if from == 0 || from == rev {
+		return indexURL(page, url.Values{"diff": {"prev"}, "oldid": {strconv.FormatInt(rev, 10)}})
+	}
+	return indexURL(page, url.Values{"diff": {strconv.FormatInt(rev, 10)}, "oldid": {strconv.FormatInt(from, 10)}})
+}
+
+func indexURL(page string, args url.Values) string {
+	page, _ = Selector(page)
+	lang, title := Language(page)
+	args.Set("title", title)
+	return "https://" + lang + ".wikipedia.org/w/index.php?" + args.Encode()
+}
+
+// revisionDetails looks up when a revision was made, and by whom
+func revisionDetails(ctx context.Context, api string, rev int64) (time.Time, string, error) {
+	u, err := url.Parse(api)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	u.RawQuery = url.Values{
+		"action":        {"query"},
+		"prop":          {"revisions"},
+		"rvprop":        {"ids|timestamp|user"},
+		"revids":        {strconv.FormatInt(rev, 10)},
+		"format":        {"json"},
+		"formatversion": {"2"},
+	}.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	r, err := do(ctx, req)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		return time.Time{}, "", fmt.Errorf("revision %d: wikipedia API error (status: %d)", rev, r.StatusCode)
+	}
+
+	var res apiQuery
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return time.Time{}, "", fmt.Errorf("revision %d: %s", rev, err)
+	}
+	for _, p := range res.Query.Pages {
+		for _, r := range p.Revisions {
+			if r.RevID == rev {
+				return r.Timestamp.UTC(), r.User, nil
+			}
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("revision %d: not found", rev)
+}
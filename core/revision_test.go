@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRevisionURL(t *testing.T) {
+	for _, c := range []struct {
+		Page       string
+		Rev        int64
+		From       int64
+		Rev1, Diff string
+	}{
+		{
+			Page: "Git",
+			Rev:  806191967,
+			Rev1: "https://en.wikipedia.org/w/index.php?oldid=806191967&title=Git",
+			Diff: "https://en.wikipedia.org/w/index.php?diff=prev&oldid=806191967&title=Git",
+		},
+		{
+			Page: "Git",
+			Rev:  806191967,
+			From: 805000000,
+			Rev1: "https://en.wikipedia.org/w/index.php?oldid=806191967&title=Git",
+			Diff: "https://en.wikipedia.org/w/index.php?diff=806191967&oldid=805000000&title=Git",
+		},
+		{
+			Page: "de:PostgreSQL|PostgreSQL",
+			Rev:  42,
+			Rev1: "https://de.wikipedia.org/w/index.php?oldid=42&title=PostgreSQL",
+			Diff: "https://de.wikipedia.org/w/index.php?diff=prev&oldid=42&title=PostgreSQL",
+		},
+		{
+			Page: "pypi:django",
+		},
+	} {
+		if have, want := RevisionURL(c.Page, c.Rev), c.Rev1; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := DiffURL(c.Page, c.Rev, c.From), c.Diff; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
+
+func TestRevisionDetails(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.URL.Query().Get("prop"), "revisions"; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		http.ServeFile(w, r, "./data/api/revision.json")
+	}))
+	defer s.Close()
+
+	w := Wikipedia{API: s.URL}
+	p := w.withRevision(ctx, Page{Page: "Git", Revision: 806191967})
+	if have, want := p.Editor, "Example Editor"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if have, want := p.RevisionTime, time.Date(2017, 10, 22, 19, 4, 11, 0, time.UTC); !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// unknown revision: no details, but no error either
+	p = w.withRevision(ctx, Page{Page: "Git", Revision: 1})
+	if have, want := p.Editor, ""; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Rule maps an infobox row label to an Infobox field
+type Rule struct {
+	Lang   string `json:"lang"`   // wikipedia language ("en")
+	Label  string `json:"label"`  // first cell of the row ("Stable release")
+	Field  string `json:"field"`  // see Fields
+	Layout string `json:"layout"` // see Layouts. Default "row".
+}
+
+// Rules is an ordered list. For every field the first row found in the
+// infobox wins, so a row further down the page, such as in a navigation box,
+// can't override it. That goes for the versions as well: before rules the
+// last "Stable release" row won.
+type Rules []Rule
+
+// Fields are the valid Rule.Field values
+var Fields = []string{"stable", "preview", "homepage", "developer", "license", "written_in", "os", "repository"}
+
+// Layouts are the valid Rule.Layout values:
+//   - row: the value is the second cell of the row with the label
+//   - next: the value is the first cell of the row after the label
+//   - nested: the value is a table in the row with the label, or in the row
+//     after it. Every row of that table is a line.
+var Layouts = []string{"row", "next", "nested"}
+
+// DefaultRules are used when there is no rules file
+var DefaultRules = Rules{
+	{Lang: "en", Label: "Stable release", Field: "stable"},
+	{Lang: "en", Label: "Latest release", Field: "stable"},
+	{Lang: "en", Label: "Last release", Field: "stable"},
+	{Lang: "en", Label: "Stable release(s) [±]", Field: "stable", Layout: "nested"},
+	{Lang: "en", Label: "Preview release", Field: "preview"},
+	{Lang: "en", Label: "Preview release(s) [±]", Field: "preview", Layout: "nested"},
+	{Lang: "en", Label: "Official website", Field: "homepage"},
+	{Lang: "en", Label: "Website", Field: "homepage"},
+	{Lang: "en", Label: "Developer(s)", Field: "developer"},
+	{Lang: "en", Label: "Developer", Field: "developer"},
+	{Lang: "en", Label: "License", Field: "license"},
+	{Lang: "en", Label: "Licence", Field: "license"},
+	{Lang: "en", Label: "Written in", Field: "written_in"},
+	{Lang: "en", Label: "Operating system", Field: "os"},
+	{Lang: "en", Label: "OS", Field: "os"},
+	{Lang: "en", Label: "Repository", Field: "repository"},
+
+	{Lang: "de", Label: "Aktuelle Version", Field: "stable"},
+	{Lang: "de", Label: "Letzte Version", Field: "stable"},
+	{Lang: "de", Label: "Aktuelle Vorabversion", Field: "preview"},
+	{Lang: "de", Label: "Vorabversion", Field: "preview"},
+	{Lang: "de", Label: "Website", Field: "homepage"},
+	{Lang: "de", Label: "Webseite", Field: "homepage"},
+	{Lang: "de", Label: "Entwickler", Field: "developer"},
+	{Lang: "de", Label: "Lizenz", Field: "license"},
+	{Lang: "de", Label: "Programmiersprache", Field: "written_in"},
+	{Lang: "de", Label: "Programmier\u00adsprache", Field: "written_in"},
+	{Lang: "de", Label: "Betriebssystem", Field: "os"},
+
+	{Lang: "fr", Label: "Dernière version", Field: "stable"},
+	{Lang: "fr", Label: "Dernière version stable", Field: "stable"},
+	{Lang: "fr", Label: "Version avancée", Field: "preview"},
+	{Lang: "fr", Label: "Dernière version avancée", Field: "preview"},
+	{Lang: "fr", Label: "Site web", Field: "homepage"},
+	{Lang: "fr", Label: "Site internet", Field: "homepage"},
+	{Lang: "fr", Label: "Développé par", Field: "developer"},
+	{Lang: "fr", Label: "Développeur", Field: "developer"},
+	{Lang: "fr", Label: "Licence", Field: "license"},
+	{Lang: "fr", Label: "Écrit en", Field: "written_in"},
+	{Lang: "fr", Label: "Système d'exploitation", Field: "os"},
+	{Lang: "fr", Label: "Environnement", Field: "os"},
+	{Lang: "fr", Label: "Dépôt", Field: "repository"},
+}
+
+// LoadRules reads a JSON rules file
+func LoadRules(filename string) (Rules, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRules(f)
+}
+
+// ReadRules reads JSON rules. The format is a list of Rule objects:
+//
+//	[{"lang": "en", "label": "Stable release", "field": "stable"}, ...]
+func ReadRules(r io.Reader) (Rules, error) {
+	var rs Rules
+	if err := json.NewDecoder(r).Decode(&rs); err != nil {
+		return nil, err
+	}
+	return rs, rs.Validate()
+}
+
+// ParserVersion changes whenever the infobox parsing changes in a way which
+// can give different values for the same page.
+const ParserVersion = 1
+
+// Version identifies the parser and these rules. Pages store it, so an
+// unchanged revision is only reparsed when either changed.
+func (rs Rules) Version() string {
+	b, _ := json.Marshal(rs)
+	sum := sha1.Sum(b)
+	return fmt.Sprintf("%d-%x", ParserVersion, sum[:4])
+}
+
+// Validate checks all fields and layouts are known
+func (rs Rules) Validate() error {
+	for i, r := range rs {
+		if !languages[r.Lang] {
+			return fmt.Errorf("rule %d (%q): unsupported language %q", i, r.Label, r.Lang)
+		}
+		if r.Label == "" {
+			return fmt.Errorf("rule %d: no label", i)
+		}
+		if !contains(Fields, r.Field) {
+			return fmt.Errorf("rule %d (%q): unknown field %q", i, r.Label, r.Field)
+		}
+		if r.Layout != "" && !contains(Layouts, r.Layout) {
+			return fmt.Errorf("rule %d (%q): unknown layout %q", i, r.Label, r.Layout)
+		}
+	}
+	return nil
+}
+
+// Parse finds the interesting rows in the infobox of a wikipedia in the given
+// language. If the page has more than one infobox it uses the first one.
+func (rs Rules) Parse(n io.Reader, lang string) (Infobox, error) {
+	ib, _, err := rs.parse(n, lang)
+	return ib, err
+}
+
+// ParseAll is Parse for every infobox on the page, with their captions. A page
+// without a recognizable infobox gives a single Infobox, from all tables.
+func (rs Rules) ParseAll(n io.Reader, lang string) ([]Infobox, error) {
+	var body bytes.Buffer
+	ts, err := ScanInfoboxes(io.TeeReader(n, &body))
+	if err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		ts, err := FindTables(&body)
+		if err != nil {
+			return nil, err
+		}
+		ib, _, err := rs.parseTables(ts, lang)
+		if err != nil {
+			return nil, err
+		}
+		return []Infobox{ib}, nil
+	}
+	var ibs []Infobox
+	for _, t := range ts {
+		ib, _, err := rs.parseTables([]Table{t}, lang)
+		if err != nil {
+			return nil, err
+		}
+		ib.Caption = t.Caption
+		ibs = append(ibs, ib)
+	}
+	return ibs, nil
+}
+
+// SelectInfobox finds the infobox with the given caption. Case, spaces, and
+// underscores don't matter.
+func SelectInfobox(ibs []Infobox, caption string) (Infobox, bool) {
+	for _, ib := range ibs {
+		if sameCaption(ib.Caption, caption) {
+			return ib, true
+		}
+	}
+	return Infobox{}, false
+}
+
+// parse also returns which rules matched, by index. Nothing after the
+// infobox is read.
+func (rs Rules) parse(n io.Reader, lang string) (Infobox, []int, error) {
+	// keep what we read, in case we need the fallback
+	var body bytes.Buffer
+	t, err := ScanInfobox(io.TeeReader(n, &body))
+	if err != nil {
+		return Infobox{}, nil, err
+	}
+	if t != nil {
+		ib, matched, err := rs.parseTables([]Table{*t}, lang)
+		ib.Caption = t.Caption
+		return ib, matched, err
+	}
+	// no (recognizable) infobox, try every table
+	ts, err := FindTables(io.MultiReader(&body, n))
+	if err != nil {
+		return Infobox{}, nil, err
+	}
+	return rs.parseTables(ts, lang)
+}
+
+func (rs Rules) parseTables(ts []Table, lang string) (Infobox, []int, error) {
+	var (
+		ib      Infobox
+		matched []int
+	)
+	for _, t := range ts {
+		for i, r := range t.Rows {
+			if len(r) == 0 {
+				continue
+			}
+			for j, rule := range rs {
+				if rule.Lang != lang || rule.Label != r[0] {
+					continue
+				}
+				v := rule.value(t, i)
+				if v == "" {
+					continue
+				}
+				dst, err := ib.field(rule.Field)
+				if err != nil {
+					return ib, matched, fmt.Errorf("rule %d (%q): %s", j, rule.Label, err)
+				}
+				if *dst == "" {
+					*dst = v
+				}
+				matched = append(matched, j)
+			}
+		}
+	}
+	ib.Repository = stripScheme(ib.Repository)
+	return ib, matched, nil
+}
+
+// value reads the value of row i
+func (r Rule) value(t Table, i int) string {
+	switch r.Layout {
+	case "next":
+		return nextRow(t, i)
+	case "nested":
+		n, ok := t.Nested[i]
+		if !ok {
+			if n, ok = t.Nested[i+1]; !ok {
+				return ""
+			}
+		}
+		var lines []string
+		for _, r := range n.Rows {
+			lines = append(lines, strings.Join(r, " "))
+		}
+		return cleanSpace(strings.Join(lines, "\n"))
+	default:
+		if len(t.Rows[i]) > 1 {
+			return t.Rows[i][1]
+		}
+		return ""
+	}
+}
+
+// first column of the row after row i
+func nextRow(t Table, i int) string {
+	if len(t.Rows) > i+1 {
+		if r := t.Rows[i+1]; len(r) > 0 {
+			return r[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,318 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// rulesFixtures are all HTML pages in ./data/, with what we expect to find.
+var rulesFixtures = []struct {
+	Filename string
+	Lang     string
+	Want     Infobox
+}{
+	{
+		Filename: "git.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:    "Git",
+			Stable:     "2.14.2 / 22 September 2017",
+			Homepage:   "git-scm.com",
+			Developer:  "Junio Hamano and others",
+			License:    "GNU GPL v2 and GNU LGPL v2.1",
+			WrittenIn:  "C, Shell, Perl, Tcl, Python",
+			OS:         "POSIX: Linux, Windows, macOS",
+			Repository: "git-scm.com/downloads",
+		},
+	},
+	{
+		Filename: "debian.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:   "Debian",
+			Stable:    "9.2 (Stretch)",
+			Homepage:  "www.debian.org",
+			Developer: "Debian Project (Software in the Public Interest)",
+			License:   "DFSG-compliant\n(free software licenses)",
+		},
+	},
+	{
+		Filename: "postgresql.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:    "PostgreSQL",
+			Stable:     "10.0 / 5 October 2017",
+			Homepage:   "postgresql.org",
+			Developer:  "PostgreSQL Global Development Group",
+			License:    "PostgreSQL License (free and open-source, permissive)",
+			WrittenIn:  "C (pgAdmin: wxWidgets)",
+			OS:         "Most Unix-like operating systems and Windows",
+			Repository: "git.postgresql.org/gitweb/?p=postgresql.git",
+		},
+	},
+	{
+		Filename: "python.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:   "Python",
+			Stable:    "3.6.3 / 3 October 2017\n2.7.14 / 16 September 2017",
+			Homepage:  "www.python.org",
+			Developer: "Python Software Foundation",
+			License:   "Python Software Foundation License",
+			OS:        "Cross-platform",
+		},
+	},
+	{
+		Filename: "firefox.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:   "Mozilla Firefox",
+			Stable:    "Standard 56.0.2 / 26 October 2017\nESR 52.4.1 / 9 October 2017",
+			Preview:   "Beta & Developer Edition 57.0beta / September 26, 2017 semiweekly release\nNightly 58.0a1 / September 22, 2017 daily release",
+			Homepage:  "mozilla.org/firefox",
+			Developer: "Mozilla Foundation and contributors\nMozilla Corporation",
+			License:   "MPL 2.0",
+			WrittenIn: "C++, JavaScript, HTML, C, Rust",
+			OS:        "Windows, macOS, Linux, Android, iOS (Unofficial ports to BSDs, Solaris, OpenSolaris, illumos)",
+		},
+	},
+	{
+		Filename: "pine.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:   "Pine",
+			Stable:    "4.64",
+			Homepage:  "www.washington.edu/pine",
+			Developer: "University of Washington",
+			License:   "Freeware",
+			OS:        "Windows, Unix, Linux",
+		},
+	},
+	{
+		Filename: "de_postgresql.html",
+		Lang:     "de",
+		Want: Infobox{
+			Caption:   "PostgreSQL",
+			Stable:    "10.0 (5. Oktober 2017)",
+			Preview:   "11 Beta 1 (24. Mai 2018)",
+			Homepage:  "www.postgresql.org",
+			Developer: "PostgreSQL Global Development Group",
+			License:   "PostgreSQL-Lizenz",
+			WrittenIn: "C",
+			OS:        "Unixoide, Windows",
+		},
+	},
+	{
+		Filename: "fr_postgresql.html",
+		Lang:     "fr",
+		Want: Infobox{
+			Stable:     "10.0 (5 octobre 2017)",
+			Preview:    "10 beta 4 (31 août 2017)",
+			Homepage:   "www.postgresql.org",
+			Developer:  "PostgreSQL Global Development Group",
+			License:    "Licence PostgreSQL",
+			WrittenIn:  "C",
+			OS:         "Multiplate-forme",
+			Repository: "git.postgresql.org/gitweb/?p=postgresql.git",
+		},
+	},
+	{
+		Filename: "netsurf.html",
+		Lang:     "en",
+		Want: Infobox{
+			Caption:   "NetSurf",
+			Stable:    "3.7 / 21 September 2017",
+			Homepage:  "www.netsurf-browser.org",
+			Developer: "The NetSurf Developers",
+			License:   "GPLv2",
+			WrittenIn: "C",
+			OS:        "RISC OS, Linux, Haiku, AmigaOS",
+		},
+	},
+	{
+		Filename: "de_firefox.html",
+		Lang:     "de",
+		Want: Infobox{
+			Caption:   "Mozilla Firefox",
+			Stable:    "56.0.2 (26. Oktober 2017)",
+			Preview:   "57.0 Beta 14 (2. November 2017)",
+			Homepage:  "www.mozilla.org/de/firefox",
+			Developer: "Mozilla Foundation",
+			License:   "MPL 2.0",
+			WrittenIn: "C++, JavaScript, Rust",
+			OS:        "Windows, macOS, Linux, Android, iOS",
+		},
+	},
+	{
+		Filename: "de_netscape.html",
+		Lang:     "de",
+		Want: Infobox{
+			Caption:   "Netscape Navigator",
+			Stable:    "9.0.0.6 (20. Februar 2008)",
+			Homepage:  "browser.netscape.com",
+			Developer: "Netscape Communications",
+			License:   "Proprietär",
+			OS:        "Windows, macOS, Linux",
+		},
+	},
+	{
+		Filename: "fr_firefox.html",
+		Lang:     "fr",
+		Want: Infobox{
+			Stable:    "56.0.2 (26 octobre 2017)",
+			Preview:   "57.0b14 (2 novembre 2017)",
+			Homepage:  "www.mozilla.org/fr/firefox",
+			Developer: "Fondation Mozilla",
+			License:   "MPL 2.0",
+			WrittenIn: "C++, JavaScript et Rust",
+			OS:        "Windows, macOS, Linux, Android et iOS",
+		},
+	},
+}
+
+// TestDefaultRules checks every fixture, and that every rule is used by at
+// least one fixture.
+func TestDefaultRules(t *testing.T) {
+	// no fixture has these yet
+	untested := map[string]bool{
+		"Preview release": true,
+	}
+
+	used := map[int]bool{}
+	for _, c := range rulesFixtures {
+		r, err := os.Open("./data/" + c.Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, matched, err := DefaultRules.parse(r, c.Lang)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := c.Want; have != want {
+			t.Errorf("%s: have %#v, want %#v", c.Filename, have, want)
+		}
+		for _, i := range matched {
+			used[i] = true
+		}
+	}
+	for i, r := range DefaultRules {
+		if !used[i] && !untested[r.Label] {
+			t.Errorf("rule %d (%s %q) doesn't match any fixture", i, r.Lang, r.Label)
+		}
+	}
+	if err := DefaultRules.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	rs, err := ReadRules(strings.NewReader(`[
+		{"lang": "en", "label": "Current version", "field": "stable"},
+		{"lang": "en", "label": "Stable release(s) [±]", "field": "stable", "layout": "next"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(rs), 2; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	// "next" gives the nested table as text
+	r, err := os.Open("./data/firefox.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ib, err := rs.Parse(r, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ib.Stable, "Standard 56.0.2 / 26 October 2017\nESR 52.4.1 / 9 October 2017"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	for doc, want := range map[string]string{
+		`[{"lang": "en", "label": "Foo", "field": "nosuch"}]`:                   `rule 0 ("Foo"): unknown field "nosuch"`,
+		`[{"lang": "en", "label": "Foo", "field": "os", "layout": "diagonal"}]`: `rule 0 ("Foo"): unknown layout "diagonal"`,
+		`[{"lang": "xx", "label": "Foo", "field": "os"}]`:                       `rule 0 ("Foo"): unsupported language "xx"`,
+		`[{"lang": "en", "field": "os"}]`:                                       `rule 0: no label`,
+	} {
+		_, err := ReadRules(strings.NewReader(doc))
+		if err == nil {
+			t.Fatalf("expected an error for %s", doc)
+		}
+		if have := err.Error(); have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	r, err := os.Open("./data/postgresql.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ibs, err := DefaultRules.ParseAll(r, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(ibs), 2; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if have, want := ibs[0].Stable, "10.0 / 5 October 2017"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	ib, ok := SelectInfobox(ibs, "postgresql_license")
+	if !ok {
+		t.Fatal("no infobox")
+	}
+	if have, want := ib.Caption, "PostgreSQL License"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if have, want := ib.Stable, ""; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestRulesUnknownField(t *testing.T) {
+	// rules which didn't go through Validate()
+	rs := Rules{{Lang: "en", Label: "Stable release", Field: "nosuch"}}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./data/git.html")
+	}))
+	defer s.Close()
+	_, err := GetPageRules(context.Background(), "Git", s.URL+"/wiki/Git", rs)
+	if have, want := fmt.Sprint(err), `rule 0 ("Stable release"): unknown field "nosuch"`; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestRulesFirstRowWins(t *testing.T) {
+	// Before rules the last "Stable release" row won. Now, for every field,
+	// the first matching row wins.
+	page := `<table class="infobox">
+<tr><th scope="row">Stable release</th><td>1.0</td></tr>
+<tr><th scope="row">Latest release</th><td>2.0</td></tr>
+<tr><th scope="row">Stable release</th><td>3.0</td></tr>
+<tr><th scope="row">Website</th><td>example.com</td></tr>
+<tr><th scope="row">Official website</th><td>example.org</td></tr>
+</table>`
+	ib, err := DefaultRules.Parse(strings.NewReader(page), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ib.Stable, "1.0"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if have, want := ib.Homepage, "example.com"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// search is DB.Search for a list of pages: pages containing q, case and
+// underscore insensitive. Pages starting with q come first.
+func search(pages []string, q string, limit int) []string {
+	q = searchKey(q)
+	if q == "" || limit <= 0 {
+		return nil
+	}
+	var prefix, other []string
+	for _, p := range pages {
+		k := searchKey(p)
+		switch {
+		case strings.HasPrefix(k, q):
+			prefix = append(prefix, p)
+		case strings.Contains(k, q):
+			other = append(other, p)
+		}
+	}
+	sort.Strings(prefix)
+	sort.Strings(other)
+	res := append(prefix, other...)
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// searchKey is lowercase, with underscores for spaces, like page names
+func searchKey(s string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(s), " ", "_", -1))
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	pages := []string{
+		"Debian",
+		"Git_(software)",
+		"Glasgow_Haskell_Compiler",
+		"PostgreSQL",
+		"de:PostgreSQL",
+		"pypi:django",
+	}
+	for q, want := range map[string][]string{
+		"g":               {"Git_(software)", "Glasgow_Haskell_Compiler", "PostgreSQL", "de:PostgreSQL", "pypi:django"},
+		"gi":              {"Git_(software)"},
+		"POSTGRES":        {"PostgreSQL", "de:PostgreSQL"},
+		"glasgow haskell": {"Glasgow_Haskell_Compiler"},
+		"pypi:":           {"pypi:django"},
+		"nosuch":          nil,
+		"":                nil,
+		" ":               nil,
+	} {
+		if have := search(pages, q, 10); !reflect.DeepEqual(have, want) {
+			t.Errorf("%q: have %q, want %q", q, have, want)
+		}
+	}
+	if have, want := search(pages, "g", 2), []string{"Git_(software)", "Glasgow_Haskell_Compiler"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Source spiders pages from a single provider.
+type Source interface {
+	// Fetch loads the current version of page. page includes the namespace
+	// prefix, if any ("pypi:django").
+	Fetch(ctx context.Context, page string) (Page, error)
+}
+
+// Refresher is a Source which can cheaply check whether a page changed since
+// it was last fetched. If it didn't it returns last, with a new T.
+type Refresher interface {
+	Refresh(ctx context.Context, last Page) (Page, error)
+}
+
+// Refresh uses the Refresher of a source if it has one, and Fetch otherwise.
+func Refresh(ctx context.Context, src Source, last Page) (Page, error) {
+	if r, ok := src.(Refresher); ok {
+		return r.Refresh(ctx, last)
+	}
+	return src.Fetch(ctx, last.Page)
+}
+
+// Sources dispatches on the namespace of a page ("github:owner/repo"). Pages
+// without a known namespace are wikipedia pages, and go to the "" source.
+type Sources map[string]Source
+
+// Fetch implements Source
+func (s Sources) Fetch(ctx context.Context, page string) (Page, error) {
+	ns, _ := Namespace(page)
+	src, ok := s[ns]
+	if !ok {
+		return Page{Page: page}, fmt.Errorf("%q: no source for %q", page, ns)
+	}
+	return src.Fetch(ctx, page)
+}
+
+// Refresh implements Refresher
+func (s Sources) Refresh(ctx context.Context, last Page) (Page, error) {
+	ns, _ := Namespace(last.Page)
+	src, ok := s[ns]
+	if !ok {
+		return Page{Page: last.Page}, fmt.Errorf("%q: no source for %q", last.Page, ns)
+	}
+	return Refresh(ctx, src, last)
+}
+
+var (
+	_ Source    = Sources{}
+	_ Refresher = Sources{}
+)
+
+// DefaultSources uses the public registry APIs, and wiki for wikipedia pages.
+func DefaultSources(wiki Source) Sources {
+	return Sources{
+		"":       wiki,
+		"github": GitHub{API: GitHubAPI},
+		"pypi":   PyPI{API: PyPIAPI},
+		"npm":    NPM{API: NPMAPI},
+		"crates": Crates{API: CratesAPI},
+	}
+}
+
+// namespaces are the known page prefixes, with their human readable URL
+var namespaces = map[string]func(id string) string{
+	"github": func(id string) string { return "https://github.com/" + id },
+	"pypi":   func(id string) string { return "https://pypi.org/project/" + id + "/" },
+	"npm":    func(id string) string { return "https://www.npmjs.com/package/" + id },
+	"crates": func(id string) string { return "https://crates.io/crates/" + id },
+}
+
+// Namespace splits "github:owner/repo" into "github" and "owner/repo". Pages
+// without a known namespace are wikipedia pages, and return "" as namespace.
+func Namespace(page string) (string, string) {
+	if i := strings.Index(page, ":"); i > 0 {
+		if _, ok := namespaces[page[:i]]; ok {
+			return page[:i], page[i+1:]
+		}
+	}
+	return "", page
+}
+
+// PageURL is the human readable URL of a page: the wikipedia article or the
+// project page on the registry.
+func PageURL(page string) string {
+	ns, id := Namespace(page)
+	if u, ok := namespaces[ns]; ok {
+		return u(id)
+	}
+	return WikiURL(page)
+}
+
+// Wikipedia reads the rendered HTML of wikipedia articles
+type Wikipedia struct {
+	Rules Rules  // infobox rules. DefaultRules if nil.
+	API   string // api.php URL, for revision details. The page's wikipedia if empty.
+}
+
+func (w Wikipedia) Fetch(ctx context.Context, page string) (Page, error) {
+	p, err := GetPageRules(ctx, page, WikiURL(page), w.rules())
+	if err != nil {
+		return p, err
+	}
+	return w.withRevision(ctx, p), nil
+}
+
+func (w Wikipedia) Refresh(ctx context.Context, last Page) (Page, error) {
+	p, err := RefreshPage(ctx, last, WikiURL(last.Page), w.rules())
+	if err != nil {
+		return p, err
+	}
+	return w.withRevision(ctx, p), nil
+}
+
+// withRevision adds when the revision was made, and by whom. That's only
+// nice to have, so API errors are ignored.
+func (w Wikipedia) withRevision(ctx context.Context, p Page) Page {
+	if p.Revision == 0 || p.Editor != "" {
+		return p
+	}
+	api := w.API
+	if api == "" {
+		lang, _ := Language(p.Page)
+		api = WikiAPIURL(lang)
+	}
+	if t, editor, err := revisionDetails(ctx, api, p.Revision); err == nil {
+		p.RevisionTime, p.Editor = t, editor
+	}
+	return p
+}
+
+func (w Wikipedia) rules() Rules {
+	if w.Rules == nil {
+		return DefaultRules
+	}
+	return w.Rules
+}
+
+var _ Refresher = Wikipedia{}
+
+// Wikitext reads the infobox wikitext via the MediaWiki API
+type Wikitext struct {
+	API string // api.php URL
+}
+
+func (w Wikitext) Fetch(ctx context.Context, page string) (Page, error) {
+	return GetWikitext(ctx, page, w.API)
+}
+
+// Wikidata reads versions from the Wikidata item linked to the article
+type Wikidata struct {
+	API string // api.php URL
+}
+
+func (w Wikidata) Fetch(ctx context.Context, page string) (Page, error) {
+	return GetWikidata(ctx, page, w.API)
+}
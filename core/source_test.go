@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	type cas struct {
+		Page, NS, ID string
+	}
+	for _, c := range []cas{
+		{"Debian", "", "Debian"},
+		{"github:golang/go", "github", "golang/go"},
+		{"pypi:django", "pypi", "django"},
+		{"npm:@angular/core", "npm", "@angular/core"},
+		{"crates:serde", "crates", "serde"},
+		{"Star_Wars:_Battlefront", "", "Star_Wars:_Battlefront"},
+	} {
+		ns, id := Namespace(c.Page)
+		if have, want := ns, c.NS; have != want {
+			t.Errorf("%q: have %q, want %q", c.Page, have, want)
+		}
+		if have, want := id, c.ID; have != want {
+			t.Errorf("%q: have %q, want %q", c.Page, have, want)
+		}
+	}
+}
+
+func TestPageURL(t *testing.T) {
+	for page, want := range map[string]string{
+		"Debian":           "https://en.wikipedia.org/wiki/Debian",
+		"github:golang/go": "https://github.com/golang/go",
+		"pypi:django":      "https://pypi.org/project/django/",
+	} {
+		if have := PageURL(page); have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
@@ -0,0 +1,613 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3" // registers "sqlite3"
+)
+
+// sqliteTime is how timestamps are stored. Fixed width, so they sort as
+// strings.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteMigrations are the same as migrations, but for SQLite. There are no
+// old SQLite databases, so it starts with the current schema.
+var sqliteMigrations = []string{
+	// 1: everything
+	`
+CREATE TABLE page
+    ( page text NOT NULL
+    , timestamp text NOT NULL
+    , stable_version text NOT NULL
+    , releases text NOT NULL DEFAULT '[]'
+    , release_date text
+    , preview_version text NOT NULL DEFAULT ''
+    , preview_releases text NOT NULL DEFAULT '[]'
+    , homepage text NOT NULL
+    , wikidata text NOT NULL DEFAULT ''
+    , developer text NOT NULL DEFAULT ''
+    , license text NOT NULL DEFAULT ''
+    , written_in text NOT NULL DEFAULT ''
+    , os text NOT NULL DEFAULT ''
+    , repository text NOT NULL DEFAULT ''
+    , etag text NOT NULL DEFAULT ''
+    , last_modified text NOT NULL DEFAULT ''
+    , revision integer NOT NULL DEFAULT 0
+    , revision_time text
+    , editor text NOT NULL DEFAULT ''
+    );
+CREATE INDEX page_page ON page (page, timestamp);
+
+CREATE VIEW updates
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+    FROM (
+        SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+            , lag(stable_version) OVER w AS prev
+            , lag(preview_version) OVER w AS prev_preview
+            , lag(developer) OVER w AS prev_developer
+            , lag(license) OVER w AS prev_license
+            , lag(written_in) OVER w AS prev_written_in
+            , lag(os) OVER w AS prev_os
+            , lag(repository) OVER w AS prev_repository
+        FROM page
+        WINDOW w AS (PARTITION BY page ORDER BY timestamp)
+    ) sub
+    WHERE prev IS NULL OR stable_version <> prev OR preview_version <> prev_preview
+        OR developer <> prev_developer OR license <> prev_license
+        OR written_in <> prev_written_in OR os <> prev_os
+        OR repository <> prev_repository;
+
+CREATE VIEW current
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor
+    FROM (
+        SELECT *, rank() OVER (
+            PARTITION BY page ORDER BY timestamp DESC
+        ) AS rnk
+        FROM updates
+    ) sub
+    WHERE rnk=1;
+
+CREATE TABLE curated
+    ( id text NOT NULL UNIQUE
+    , created text NOT NULL
+    , used int NOT NULL default 0
+    , lastused text NOT NULL
+    , lastupdated text NOT NULL
+    , title text NOT NULL default ''
+    );
+
+CREATE TABLE curated_pages
+    ( curated_id text NOT NULL
+    , page text NOT NULL
+    , UNIQUE (curated_id, page)
+    );
+
+CREATE TABLE pending
+    ( page text NOT NULL UNIQUE
+    , since text NOT NULL
+    , seen int NOT NULL
+    , version text NOT NULL
+    );
+`,
+	// 2: parser version
+	`
+ALTER TABLE page ADD COLUMN parser text NOT NULL DEFAULT '';
+
+DROP VIEW IF EXISTS current;
+DROP VIEW IF EXISTS updates;
+
+CREATE VIEW updates
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+    FROM (
+        SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+            , lag(stable_version) OVER w AS prev
+            , lag(preview_version) OVER w AS prev_preview
+            , lag(developer) OVER w AS prev_developer
+            , lag(license) OVER w AS prev_license
+            , lag(written_in) OVER w AS prev_written_in
+            , lag(os) OVER w AS prev_os
+            , lag(repository) OVER w AS prev_repository
+        FROM page
+        WINDOW w AS (PARTITION BY page ORDER BY timestamp)
+    ) sub
+    WHERE prev IS NULL OR stable_version <> prev OR preview_version <> prev_preview
+        OR developer <> prev_developer OR license <> prev_license
+        OR written_in <> prev_written_in OR os <> prev_os
+        OR repository <> prev_repository;
+
+CREATE VIEW current
+AS SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+    FROM (
+        SELECT *, rank() OVER (
+            PARTITION BY page ORDER BY timestamp DESC
+        ) AS rnk
+        FROM updates
+    ) sub
+    WHERE rnk=1;
+`,
+}
+
+// SQLite is a DB in a single file. It's safe for concurrent use, but it
+// uses a single connection, so everything is serialized.
+type SQLite struct {
+	db *sql.DB
+}
+
+var _ DB = &SQLite{}
+
+// NewSQLite opens (or creates) the database file. Use Migrate() to create the
+// tables.
+func NewSQLite(file string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Close closes the database file
+func (s *SQLite) Close() {
+	s.db.Close()
+}
+
+// Migrate brings the schema up to date. It returns the version from before,
+// and the version now.
+func (s *SQLite) Migrate() (int, int, error) {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version
+			( version int NOT NULL UNIQUE
+			, applied text NOT NULL
+			)
+	`); err != nil {
+		return 0, 0, err
+	}
+	var from int
+	if err := s.db.QueryRow(`
+		SELECT coalesce(max(version), 0)
+		FROM schema_version
+	`).Scan(&from); err != nil {
+		return 0, 0, err
+	}
+	if from > len(sqliteMigrations) {
+		return from, from, fmt.Errorf("database schema version %d is newer than this program (%d)", from, len(sqliteMigrations))
+	}
+	for v := from; v < len(sqliteMigrations); v++ {
+		if err := s.migrate(v); err != nil {
+			return from, v, err
+		}
+	}
+	return from, len(sqliteMigrations), nil
+}
+
+// migrate applies migration v+1
+func (s *SQLite) migrate(v int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(sqliteMigrations[v]); err != nil {
+		return fmt.Errorf("migration %d: %s", v+1, err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO schema_version (version, applied)
+		VALUES (?, ?)
+	`, v+1, toSQLiteTime(time.Now())); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func toSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTime)
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return toSQLiteTime(t)
+}
+
+// sqliteTimeScanner reads a timestamp column. NULL is the zero time.
+type sqliteTimeScanner struct {
+	t *time.Time
+}
+
+func (s sqliteTimeScanner) Scan(v interface{}) error {
+	var str string
+	switch v := v.(type) {
+	case nil:
+		*s.t = time.Time{}
+		return nil
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("unexpected timestamp type %T", v)
+	}
+	t, err := time.Parse(sqliteTime, str)
+	if err != nil {
+		return err
+	}
+	*s.t = t.UTC()
+	return nil
+}
+
+func (s *SQLite) Recent(ctx context.Context, n int) ([]Page, error) {
+	return s.queryPages(ctx, "current", `
+		ORDER BY timestamp DESC
+		LIMIT ?
+    `, n)
+}
+
+func (s *SQLite) Last(ctx context.Context, page string) (*Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+		FROM page
+		WHERE page=?
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, page)
+	res, err := scanSQLitePage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (s *SQLite) CurrentAll(ctx context.Context) ([]Page, error) {
+	return s.queryPages(ctx, "current", `
+		ORDER BY page
+    `)
+}
+
+func (s *SQLite) Current(ctx context.Context, pages ...string) ([]Page, error) {
+	if len(pages) == 0 {
+		return nil, nil
+	}
+	in, args := sqliteIn(pages)
+	return s.queryPages(ctx, "current", `
+		WHERE page IN (`+in+`)
+		ORDER BY timestamp DESC
+    `, args...)
+}
+
+// History of a list of page. Newest first.
+func (s *SQLite) History(ctx context.Context, pages ...string) ([]Page, error) {
+	if len(pages) == 0 {
+		return nil, nil
+	}
+	in, args := sqliteIn(pages)
+	return s.queryPages(ctx, "updates", `
+		WHERE page IN (`+in+`)
+		ORDER BY timestamp DESC
+    `, args...)
+}
+
+// sqliteIn makes the placeholders for an IN (...)
+func sqliteIn(ss []string) (string, []interface{}) {
+	var (
+		in   []string
+		args []interface{}
+	)
+	for _, s := range ss {
+		in = append(in, "?")
+		args = append(args, s)
+	}
+	return strings.Join(in, ","), args
+}
+
+func (s *SQLite) queryPages(ctx context.Context, table, where string, args ...interface{}) ([]Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser
+		FROM `+table+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var es []Page
+	for rows.Next() {
+		e, err := scanSQLitePage(rows)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, *e)
+	}
+	return es, rows.Err()
+}
+
+// scanSQLitePage reads the columns from queryPages
+func scanSQLitePage(row scanner) (*Page, error) {
+	var (
+		e            Page
+		rels, prevws []byte
+		err          error
+	)
+	if err := row.Scan(&e.Page, sqliteTimeScanner{&e.T}, &e.StableVersion, &rels, sqliteTimeScanner{&e.ReleaseDate}, &e.PreviewVersion, &prevws, &e.Homepage, &e.Wikidata, &e.Developer, &e.License, &e.WrittenIn, &e.OS, &e.Repository, &e.ETag, &e.LastModified, &e.Revision, sqliteTimeScanner{&e.RevisionTime}, &e.Editor, &e.Parser); err != nil {
+		return nil, err
+	}
+	if e.Releases, err = unmarshalReleases(rels); err != nil {
+		return nil, err
+	}
+	if e.PreviewReleases, err = unmarshalReleases(prevws); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *SQLite) Store(ctx context.Context, e Page) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rels, err := marshalReleases(e.Releases)
+	if err != nil {
+		return err
+	}
+	prevws, err := marshalReleases(e.PreviewReleases)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+	INSERT INTO page
+		(page, timestamp, stable_version, releases, release_date, preview_version, preview_releases, homepage, wikidata, developer, license, written_in, os, repository, etag, last_modified, revision, revision_time, editor, parser)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, e.Page, toSQLiteTime(e.T), e.StableVersion, rels, nullTime(e.ReleaseDate), e.PreviewVersion, prevws, e.Homepage, e.Wikidata, e.Developer, e.License, e.WrittenIn, e.OS, e.Repository, e.ETag, e.LastModified, e.Revision, nullTime(e.RevisionTime), e.Editor, e.Parser)
+	return err
+}
+
+func (s *SQLite) Known(ctx context.Context) ([]string, error) {
+	return s.queryStrings(ctx, `
+		SELECT DISTINCT(page)
+		FROM updates
+		ORDER BY page`)
+}
+
+func (s *SQLite) Search(ctx context.Context, q string, limit int) ([]string, error) {
+	q = likeEscape(searchKey(q))
+	if q == "" || limit <= 0 {
+		return nil, nil
+	}
+	return s.queryStrings(ctx, `
+		SELECT page
+		FROM page
+		WHERE lower(page) LIKE ? ESCAPE '\'
+		GROUP BY page
+		ORDER BY lower(page) LIKE ? ESCAPE '\' DESC, page
+		LIMIT ?`,
+		"%"+q+"%",
+		q+"%",
+		limit,
+	)
+}
+
+// queryStrings runs a query which returns a single text column
+func (s *SQLite) queryStrings(ctx context.Context, q string, args ...interface{}) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ps []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, rows.Err()
+}
+
+func (s *SQLite) CreateCurated(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	cid := id.String()
+	now := toSQLiteTime(time.Now())
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO curated (id, created, lastused, lastupdated)
+		VALUES (?, ?, ?, ?)`,
+		cid, now, now, now,
+	)
+	return cid, err
+}
+
+func (s *SQLite) LoadCurated(ctx context.Context, id string) (*Curated, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT created, lastused, lastupdated, title
+		FROM curated
+		WHERE id=?`,
+		id,
+	)
+	cur := Curated{}
+	if err := row.Scan(sqliteTimeScanner{&cur.Created}, sqliteTimeScanner{&cur.LastUsed}, sqliteTimeScanner{&cur.LastUpdated}, &cur.CustomTitle); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	pg, err := s.queryStrings(ctx, `
+		SELECT page
+		FROM curated_pages
+		WHERE curated_id=?
+		ORDER BY page`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	cur.Pages = pg
+	return &cur, nil
+}
+
+// pages must be unique
+func (s *SQLite) CuratedSetPages(ctx context.Context, id string, pages []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE curated SET lastupdated=? WHERE id=?`, toSQLiteTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrCuratedNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM curated_pages WHERE curated_id=?`, id); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO curated_pages (curated_id, page) VALUES (?, ?)`, id, p); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) CuratedSetUsed(ctx context.Context, id string) error {
+	return s.updateCurated(ctx, `UPDATE curated SET lastused=?, used=used+1 WHERE id=?`, toSQLiteTime(time.Now()), id)
+}
+
+func (s *SQLite) CuratedSetTitle(ctx context.Context, id, title string) error {
+	return s.updateCurated(ctx, `UPDATE curated SET title=?, lastupdated=? WHERE id=?`, title, toSQLiteTime(time.Now()), id)
+}
+
+// updateCurated runs an UPDATE on a single curated row
+func (s *SQLite) updateCurated(ctx context.Context, q string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCuratedNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) LoadPending(ctx context.Context, page string) (*Pending, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT since, seen, version
+		FROM pending
+		WHERE page=?`,
+		page,
+	)
+	res, err := scanSQLitePending(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (s *SQLite) SetPending(ctx context.Context, pend Pending) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	v, err := json.Marshal(pend.Page)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO pending
+			(page, since, seen, version)
+		VALUES
+			(?, ?, ?, ?)
+		ON CONFLICT (page) DO UPDATE
+			SET since=excluded.since, seen=excluded.seen, version=excluded.version`,
+		pend.Page.Page, toSQLiteTime(pend.Since), pend.Seen, string(v),
+	)
+	return err
+}
+
+func (s *SQLite) DeletePending(ctx context.Context, page string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM pending WHERE page=?`, page)
+	return err
+}
+
+func (s *SQLite) PendingAll(ctx context.Context) ([]Pending, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT since, seen, version
+		FROM pending
+		ORDER BY since, page`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ps []Pending
+	for rows.Next() {
+		pend, err := scanSQLitePending(rows)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, *pend)
+	}
+	return ps, rows.Err()
+}
+
+func scanSQLitePending(row scanner) (*Pending, error) {
+	var (
+		pend Pending
+		v    []byte
+	)
+	if err := row.Scan(sqliteTimeScanner{&pend.Since}, &pend.Seen, &v); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(v, &pend.Page); err != nil {
+		return nil, err
+	}
+	return &pend, nil
+}
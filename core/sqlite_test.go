@@ -0,0 +1,102 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newSQLite is a migrated database. Call the func to close and remove it.
+func newSQLite(t *testing.T) (*SQLite, func()) {
+	t.Helper()
+	dir, remove := tmpDir(t)
+	s, err := NewSQLite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		remove()
+		t.Fatal(err)
+	}
+	done := func() {
+		s.Close()
+		remove()
+	}
+	if _, _, err := s.Migrate(); err != nil {
+		done()
+		t.Fatal(err)
+	}
+	return s, done
+}
+
+func TestSQLiteDB(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+	InterfaceTestDB(t, s)
+}
+
+func TestSQLiteCurated(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+	InterfaceTestCurated(t, s)
+}
+
+func TestSQLiteSearch(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+	InterfaceTestSearch(t, s)
+}
+
+func TestSQLitePending(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+	InterfaceTestPending(t, s)
+}
+
+func TestSQLiteConcurrent(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+	InterfaceTestConcurrent(t, s)
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	s, done := newSQLite(t)
+	defer done()
+
+	// newSQLite already migrated
+	from, to, err := s.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := from, len(sqliteMigrations); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := to, len(sqliteMigrations); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, done := tmpDir(t)
+	defer done()
+
+	db, err := Open("sqlite:"+filepath.Join(dir, "open.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, ok := db.(*SQLite); !ok {
+		t.Errorf("have %T, want *SQLite", db)
+	}
+
+	db, err = Open("file:"+filepath.Join(dir, "open.log"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, ok := db.(*File); !ok {
+		t.Errorf("have %T, want *File", db)
+	}
+
+	for _, u := range []string{"sqlite:", "file:", "mysql://localhost/verssion", "verssion.db"} {
+		if _, err := Open(u, 0); err == nil {
+			t.Errorf("%q: expected an error", u)
+		}
+	}
+}
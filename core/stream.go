@@ -0,0 +1,122 @@
+package core
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// ScanInfobox finds the first `table.infobox` with a streaming tokenizer,
+// and stops reading after it. Only the infobox itself is kept in memory. The
+// result is the same as the first table from FindInfoboxes. Returns nil if
+// there is no infobox.
+func ScanInfobox(r io.Reader) (*Table, error) {
+	ts, err := scanInfoboxes(r, 1)
+	if err != nil || len(ts) == 0 {
+		return nil, err
+	}
+	return &ts[0], nil
+}
+
+// ScanInfoboxes is ScanInfobox for all infoboxes. It reads the whole page,
+// but still only keeps the infoboxes in memory.
+func ScanInfoboxes(r io.Reader) ([]Table, error) {
+	return scanInfoboxes(r, -1)
+}
+
+// scanInfoboxes stops after max infoboxes, if max > 0
+func scanInfoboxes(r io.Reader, max int) ([]Table, error) {
+	z := html.NewTokenizer(r)
+	var (
+		ts    []Table
+		root  *html.Node
+		stack []*html.Node
+	)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			if root != nil {
+				// unclosed infobox, use what we have
+				ts = append(ts, infoboxTable(root))
+			}
+			return ts, nil
+		}
+
+		if root == nil {
+			if tt != html.StartTagToken {
+				continue
+			}
+			tok := z.Token()
+			if tok.Data != "table" {
+				continue
+			}
+			n := tokenNode(tok)
+			if !hasClass(n, "infobox") {
+				continue
+			}
+			root = n
+			stack = []*html.Node{root}
+			continue
+		}
+
+		tok := z.Token()
+		top := stack[len(stack)-1]
+		switch tt {
+		case html.TextToken:
+			top.AppendChild(&html.Node{Type: html.TextNode, Data: tok.Data})
+		case html.SelfClosingTagToken:
+			top.AppendChild(tokenNode(tok))
+		case html.StartTagToken:
+			n := tokenNode(tok)
+			top.AppendChild(n)
+			if !voidTag[tok.Data] {
+				stack = append(stack, n)
+			}
+		case html.EndTagToken:
+			// close up to the matching element, which also closes any
+			// unclosed elements below it. Stray end tags are ignored.
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].Data == tok.Data {
+					stack = stack[:i]
+					break
+				}
+			}
+			if len(stack) == 0 {
+				ts = append(ts, infoboxTable(root))
+				root = nil
+				if max > 0 && len(ts) >= max {
+					return ts, nil
+				}
+			}
+		}
+	}
+}
+
+func tokenNode(tok html.Token) *html.Node {
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: tok.Data,
+		Attr: tok.Attr,
+	}
+}
+
+// elements without an end tag
+var voidTag = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+var htmlFixtures = []string{
+	"./data/debian.html",
+	"./data/firefox.html",
+	"./data/git.html",
+	"./data/pine.html",
+	"./data/postgresql.html",
+	"./data/python.html",
+	"./data/de_postgresql.html",
+	"./data/fr_postgresql.html",
+	"./data/netsurf.html",
+	"./data/de_firefox.html",
+	"./data/de_netscape.html",
+	"./data/fr_firefox.html",
+}
+
+func TestScanInfobox(t *testing.T) {
+	for _, file := range htmlFixtures {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := FindInfoboxes(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := ScanInfobox(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ts) == 0 {
+			if have != nil {
+				t.Errorf("%s: have %#v, want nil", file, have)
+			}
+			continue
+		}
+		if have == nil {
+			t.Fatalf("%s: no infobox", file)
+		}
+		if want := ts[0]; !reflect.DeepEqual(*have, want) {
+			t.Errorf("%s: have %#v\nwant %#v", file, *have, want)
+		}
+	}
+}
+
+func TestScanInfoboxStops(t *testing.T) {
+	// anything after the infobox is not read
+	r := &failReader{
+		r: bytes.NewBufferString(`<table class="infobox"><tr><th>License</th><td>MIT</td></tr></table>` + string(make([]byte, 8192))),
+	}
+	tab, err := ScanInfobox(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := tab.Rows, [][]string{{"License", "MIT"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestParseStops(t *testing.T) {
+	r := &failReader{
+		r: bytes.NewBufferString(`<table class="infobox"><tr><th>License</th><td>MIT</td></tr></table>` + string(make([]byte, 8192))),
+	}
+	ib, err := DefaultRules.Parse(r, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ib.License, "MIT"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+// failReader fails when it's read after the underlying reader is empty
+type failReader struct {
+	r *bytes.Buffer
+}
+
+func (f *failReader) Read(p []byte) (int, error) {
+	if f.r.Len() == 0 {
+		panic("read too far")
+	}
+	return f.r.Read(p)
+}
+
+func BenchmarkFindInfoboxes(b *testing.B) {
+	benchFixture(b, func(body []byte) {
+		if _, err := FindInfoboxes(bytes.NewReader(body)); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkScanInfobox(b *testing.B) {
+	benchFixture(b, func(body []byte) {
+		if _, err := ScanInfobox(bytes.NewReader(body)); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkRulesParse(b *testing.B) {
+	benchFixture(b, func(body []byte) {
+		if _, err := DefaultRules.Parse(bytes.NewReader(body), "en"); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkFindTables(b *testing.B) {
+	benchFixture(b, func(body []byte) {
+		if _, err := FindTables(bytes.NewReader(body)); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func benchFixture(b *testing.B, f func([]byte)) {
+	body, err := ioutil.ReadFile("./data/firefox.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f(body)
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Suggest finds the known pages which look like page, best match first, at
+// most n. Case, underscores, and the usual disambiguations ("(software)")
+// don't matter, and small typos are fine.
+func Suggest(known []string, page string, n int) []string {
+	q := suggestKey(page)
+	if q == "" {
+		return nil
+	}
+	type match struct {
+		page string
+		dist int
+	}
+	var ms []match
+	for _, k := range known {
+		if k == page {
+			continue
+		}
+		key := suggestKey(k)
+		d := editDistance(q, key)
+		switch {
+		case d <= maxDistance(q):
+		case len(q) >= 3 && strings.Contains(key, q):
+			// "haskell" for "Glasgow_Haskell_Compiler"
+			d = maxDistance(q) + 1 + len(key) - len(q)
+		default:
+			continue
+		}
+		ms = append(ms, match{page: k, dist: d})
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].dist != ms[j].dist {
+			return ms[i].dist < ms[j].dist
+		}
+		return ms[i].page < ms[j].page
+	})
+	var res []string
+	for i := 0; i < len(ms) && i < n; i++ {
+		res = append(res, ms[i].page)
+	}
+	return res
+}
+
+// suggestKey is how a page looks to Suggest: "Git_(software)" and "git"
+// are both "git".
+func suggestKey(page string) string {
+	page, _ = Selector(Canonical(page))
+	if ns, id := Namespace(page); ns != "" {
+		return strings.ToLower(ns + ":" + id)
+	}
+	s := strings.ToLower(Title(page))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || unicode.IsSpace(r)
+	}), " ")
+}
+
+// maxDistance is how many typos we allow
+func maxDistance(q string) int {
+	if n := len([]rune(q)) / 4; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// editDistance is the Levenshtein distance, in runes, where swapping two
+// letters counts as a single typo.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// three rows: i-2, i-1, and i
+	pprev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if t := pprev[j-2] + 1; t < cur[j] {
+					cur[j] = t
+				}
+			}
+		}
+		pprev, prev, cur = prev, cur, pprev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
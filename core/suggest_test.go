@@ -0,0 +1,55 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	known := []string{
+		"Debian",
+		"Git_(software)",
+		"Glasgow_Haskell_Compiler",
+		"PostgreSQL",
+		"Python_(programming_language)",
+		"de:PostgreSQL",
+		"pypi:django",
+	}
+	for page, want := range map[string][]string{
+		"git":               {"Git_(software)"},
+		"Gti":               {"Git_(software)"},
+		"postgres":          {"PostgreSQL", "de:PostgreSQL"},
+		"postgresq":         {"PostgreSQL", "de:PostgreSQL"},
+		"PostgreSQL_(de)":   {"de:PostgreSQL"},
+		"python":            {"Python_(programming_language)"},
+		"Pyhton":            {"Python_(programming_language)"},
+		"haskell":           {"Glasgow_Haskell_Compiler"},
+		"Debain":            {"Debian"},
+		"pypi:djnago":       {"pypi:django"},
+		"Completely_else":   nil,
+		"":                  nil,
+		"PostgreSQL|Server": {"PostgreSQL", "de:PostgreSQL"},
+	} {
+		if have := Suggest(known, page, 3); !reflect.DeepEqual(have, want) {
+			t.Errorf("%q: have %q, want %q", page, have, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, c := range []struct {
+		A, B string
+		Want int
+	}{
+		{"", "", 0},
+		{"git", "git", 0},
+		{"git", "gti", 1},
+		{"debian", "debain", 1},
+		{"kitten", "sitting", 3},
+		{"émacs", "emacs", 1},
+	} {
+		if have := editDistance(c.A, c.B); have != c.Want {
+			t.Errorf("%q/%q: have %d, want %d", c.A, c.B, have, c.Want)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,12 +17,6 @@ const (
 	UserAgent = "verssion_bot/1.0 (https://verssion.one)"
 )
 
-var client = http.Client{
-	CheckRedirect: func(*http.Request, []*http.Request) error {
-		return http.ErrUseLastResponse
-	},
-}
-
 type ErrRedirect struct {
 	Page, To string
 }
@@ -34,11 +33,43 @@ func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("%q: no such page", e.Page)
 }
 
+// ErrNoInfobox is for a "Page#caption" selector which doesn't match any
+// infobox. Captions are the infoboxes the page does have.
+type ErrNoInfobox struct {
+	Page     string
+	Captions []string
+}
+
+func (e ErrNoInfobox) Error() string {
+	return fmt.Sprintf("%q: no such infobox (have: %s)", e.Page, strings.Join(e.Captions, ", "))
+}
+
 // GetPage downloads and parses given wikipage
-func GetPage(page, url string) (Page, error) {
+func GetPage(ctx context.Context, page, url string) (Page, error) {
+	return GetPageRules(ctx, page, url, DefaultRules)
+}
+
+// GetPageRules downloads and parses given wikipage, with custom infobox rules
+func GetPageRules(ctx context.Context, page, url string, rules Rules) (Page, error) {
+	return getPage(ctx, page, url, rules, nil)
+}
+
+// RefreshPage checks whether a page changed since we last fetched it, using
+// a conditional GET and the revision ID. If nothing changed it returns last,
+// with a new T, without parsing the page again.
+func RefreshPage(ctx context.Context, last Page, url string, rules Rules) (Page, error) {
+	return getPage(ctx, last.Page, url, rules, &last)
+}
+
+func getPage(ctx context.Context, page, url string, rules Rules, last *Page) (Page, error) {
 	p := Page{
-		Page: page,
-		T:    time.Now().UTC(),
+		Page:   page,
+		T:      time.Now().UTC(),
+		Parser: rules.Version(),
+	}
+	if last != nil && last.Parser != p.Parser {
+		// parsed differently before, so we need the body again
+		last = nil
 	}
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -46,9 +77,17 @@ func GetPage(page, url string) (Page, error) {
 		return p, err
 	}
 	req.Header.Set("User-Agent", UserAgent)
+	if last != nil {
+		if last.ETag != "" {
+			req.Header.Set("If-None-Match", last.ETag)
+		}
+		if last.LastModified != "" {
+			req.Header.Set("If-Modified-Since", last.LastModified)
+		}
+	}
 
 	// no redirects
-	r, err := client.Do(req)
+	r, err := do(ctx, req)
 	if err != nil {
 		return p, err
 	}
@@ -56,18 +95,62 @@ func GetPage(page, url string) (Page, error) {
 
 	switch code := r.StatusCode; code {
 	case 200:
-		p.StableVersion, p.Homepage = StableVersion(r.Body)
+		// This reads the whole body (up to HTTPConfig.MaxSize): the canonical
+		// link, the revision, and the disambiguation check all come before
+		// the infobox is parsed. Rules.Parse only saves building the DOM.
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return p, err
+		}
+		base, caption := Selector(page)
+		lang, _ := Language(base)
+		if to := canonicalLink(body, lang); to != "" && to != base {
+			return p, ErrRedirect{Page: page, To: WithSelector(to, caption)}
+		}
+		p.ETag, p.LastModified = r.Header.Get("ETag"), r.Header.Get("Last-Modified")
+		p.Revision = revisionID(body)
+		if last != nil && p.Revision != 0 && p.Revision == last.Revision {
+			return unchanged(*last, p), nil
+		}
+		if isDisambiguation(body) {
+			return p, ErrDisambiguation{Page: page, Candidates: disambiguationCandidates(body, lang)}
+		}
+		ib, err := parseSelected(rules, page, body, lang, caption)
+		if err != nil {
+			return p, err
+		}
+		if c := underscores(ib.Caption); caption != "" && c != caption {
+			// same infobox, spelled differently
+			return p, ErrRedirect{Page: page, To: WithSelector(base, c)}
+		}
+		p.StableVersion, p.PreviewVersion, p.Homepage = ib.Stable, ib.Preview, ib.Homepage
+		p.Developer, p.License, p.WrittenIn, p.OS = ib.Developer, ib.License, ib.WrittenIn, ib.OS
+		p.Repository = ib.Repository
 		if p.StableVersion == "" {
 			return p, fmt.Errorf("%q: no version found", page)
 		}
+		p.Releases = ParseReleases(p.StableVersion)
+		p.ReleaseDate = LatestDate(p.Releases)
+		p.PreviewReleases = ParseReleases(p.PreviewVersion)
 		return p, nil
+	case 304:
+		if last == nil {
+			return p, fmt.Errorf("%q: unexpected 304", page)
+		}
+		p.ETag, p.LastModified, p.Revision = last.ETag, last.LastModified, last.Revision
+		if e := r.Header.Get("ETag"); e != "" {
+			p.ETag = e
+		}
+		return unchanged(*last, p), nil
 	case 301:
 		loc, err := r.Location()
 		if err != nil {
 			return p, err
 		}
-		to := strings.TrimPrefix(loc.Path, "/wiki/")
-		return p, ErrRedirect{Page: page, To: to}
+		base, caption := Selector(page)
+		lang, _ := Language(base)
+		to := LangPage(lang, strings.TrimPrefix(loc.Path, "/wiki/"))
+		return p, ErrRedirect{Page: page, To: WithSelector(to, caption)}
 	case 404:
 		return p, ErrNotFound{Page: page}
 	default:
@@ -75,49 +158,127 @@ func GetPage(page, url string) (Page, error) {
 	}
 }
 
-func StableVersion(n io.Reader) (string, string) {
-	var stable, homepage string
-
-	ts, err := FindTables(n)
+// parseSelected parses the first infobox, or the one with the given caption
+func parseSelected(rules Rules, page string, body []byte, lang, caption string) (Infobox, error) {
+	if caption == "" {
+		return rules.Parse(bytes.NewReader(body), lang)
+	}
+	ibs, err := rules.ParseAll(bytes.NewReader(body), lang)
 	if err != nil {
-		return "", ""
+		return Infobox{}, err
 	}
-	for _, t := range ts {
-		for i, r := range t.Rows {
-			if len(r) == 0 {
-				continue
-			}
-			v := ""
-			if len(r) > 1 {
-				v = r[1]
-			}
-			switch k := r[0]; k {
-			case "Stable release", "Latest release", "Last release":
-				stable = v
-			case "Stable release(s) [±]":
-				// Firefox, has a table with versions. The version is in the
-				// next row.
-				if len(t.Rows) > i {
-					if nextRow := t.Rows[i+1]; len(nextRow) > 0 {
-						stable = nextRow[0]
-					}
-				}
-			case "Official website", "Website":
-				if homepage == "" && v != "" {
-					homepage = v
-				}
+	ib, ok := SelectInfobox(ibs, caption)
+	if !ok {
+		e := ErrNoInfobox{Page: page}
+		for _, ib := range ibs {
+			if ib.Caption != "" {
+				e.Captions = append(e.Captions, ib.Caption)
 			}
 		}
+		return ib, e
 	}
-	return stable, homepage
+	return ib, nil
+}
+
+// unchanged is last, with the spider time and cache fields of p
+func unchanged(last, p Page) Page {
+	last.T = p.T
+	last.ETag, last.LastModified, last.Revision = p.ETag, p.LastModified, p.Revision
+	return last
+}
+
+var revisionRe = regexp.MustCompile(`"wgRevisionId":(\d+)`)
+
+// revisionID finds the wikipedia revision of an HTML page. 0 if not found.
+func revisionID(body []byte) int64 {
+	m := revisionRe.FindSubmatch(body)
+	if m == nil {
+		return 0
+	}
+	id, _ := strconv.ParseInt(string(m[1]), 10, 64)
+	return id
+}
+
+// Infobox has the values we use from a wikipedia infobox
+type Infobox struct {
+	Caption    string // "PostgreSQL", if there is a caption
+	Stable     string
+	Preview    string
+	Homepage   string
+	Developer  string
+	License    string
+	WrittenIn  string
+	OS         string
+	Repository string
+}
+
+// StableVersion returns the stable version and the homepage
+func StableVersion(n io.Reader) (string, string) {
+	i := ParseInfobox(n)
+	return i.Stable, i.Homepage
+}
+
+// ParseInfobox finds the interesting rows in an English infobox
+func ParseInfobox(n io.Reader) Infobox {
+	return ParseInfoboxLang(n, DefaultLanguage)
+}
+
+// ParseInfoboxLang finds the interesting rows in the infobox of a wikipedia
+// in the given language, using the DefaultRules.
+func ParseInfoboxLang(n io.Reader, lang string) Infobox {
+	ib, _ := DefaultRules.Parse(n, lang)
+	return ib
+}
+
+// field gives the field for a Rule.Field
+func (ib *Infobox) field(name string) (*string, error) {
+	switch name {
+	case "stable":
+		return &ib.Stable, nil
+	case "preview":
+		return &ib.Preview, nil
+	case "homepage":
+		return &ib.Homepage, nil
+	case "developer":
+		return &ib.Developer, nil
+	case "license":
+		return &ib.License, nil
+	case "written_in":
+		return &ib.WrittenIn, nil
+	case "os":
+		return &ib.OS, nil
+	case "repository":
+		return &ib.Repository, nil
+	default:
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // title version of a wikipage path
 func Title(page string) string {
-	s := strings.Replace(page, "_", " ", -1)
+	page, caption := Selector(page)
+	lang, title := Language(page)
+	s := strings.Replace(title, "_", " ", -1)
 	// remove some common disambiguations
 	s = strings.Replace(s, " (software)", "", -1)
 	s = strings.Replace(s, " (programming language)", "", -1)
+	s = strings.Replace(s, " (Software)", "", -1)
+	s = strings.Replace(s, " (logiciel)", "", -1)
+	if caption != "" {
+		s += ": " + strings.Replace(caption, "_", " ", -1)
+	}
+	if lang != DefaultLanguage {
+		s += " (" + lang + ")"
+	}
 	return s
 }
 
@@ -1,8 +1,13 @@
 package core
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestStableVersion(t *testing.T) {
@@ -60,16 +65,237 @@ func TestStableVersion(t *testing.T) {
 	}
 }
 
+func TestParseInfoboxPreview(t *testing.T) {
+	r, err := os.Open("./data/firefox.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ib := ParseInfobox(r)
+	if have, want := ib.Preview, "Beta & Developer Edition 57.0beta / September 26, 2017 semiweekly release\nNightly 58.0a1 / September 22, 2017 daily release"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
 func TestTitle(t *testing.T) {
 	for title, want := range map[string]string{
-		"Foo":                        "Foo",
-		"Foo_bar":                    "Foo bar",
-		"Foo (not software)":         "Foo (not software)",
-		"Foo (software)":             "Foo",
-		"Foo (programming language)": "Foo",
+		"Foo":                           "Foo",
+		"Foo_bar":                       "Foo bar",
+		"Foo (not software)":            "Foo (not software)",
+		"Foo (software)":                "Foo",
+		"Foo (programming language)":    "Foo",
+		"de:PostgreSQL":                 "PostgreSQL (de)",
+		"fr:Git_(logiciel)":             "Git (fr)",
+		"PostgreSQL|PostgreSQL_License": "PostgreSQL: PostgreSQL License",
 	} {
 		if have := Title(title); have != want {
 			t.Errorf("%q: have %q, want %q", title, have, want)
 		}
 	}
 }
+
+func TestParseInfoboxLang(t *testing.T) {
+	type cas struct {
+		Filename, Lang            string
+		Stable, Preview, Homepage string
+	}
+	for _, c := range []cas{
+		{
+			Filename: "de_postgresql.html",
+			Lang:     "de",
+			Stable:   "10.0 (5. Oktober 2017)",
+			Preview:  "11 Beta 1 (24. Mai 2018)",
+			Homepage: "www.postgresql.org",
+		},
+		{
+			Filename: "fr_postgresql.html",
+			Lang:     "fr",
+			Stable:   "10.0 (5 octobre 2017)",
+			Preview:  "10 beta 4 (31 août 2017)",
+			Homepage: "www.postgresql.org",
+		},
+		{
+			// only "Website" is the same in English
+			Filename: "de_postgresql.html",
+			Lang:     "en",
+			Homepage: "www.postgresql.org",
+		},
+	} {
+		r, err := os.Open("./data/" + c.Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		ib := ParseInfoboxLang(r, c.Lang)
+		if have, want := ib.Stable, c.Stable; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := ib.Preview, c.Preview; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := ib.Homepage, c.Homepage; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
+
+func TestRefreshPage(t *testing.T) {
+	ctx := context.Background()
+	body, err := ioutil.ReadFile("./data/git.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var etags bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etags {
+			if r.Header.Get("If-None-Match") == `"rev-806191967"` {
+				w.WriteHeader(304)
+				return
+			}
+			w.Header().Set("ETag", `"rev-806191967"`)
+		}
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	etags = true
+	p, err := GetPage(ctx, "Git", s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.ETag, `"rev-806191967"`; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if have, want := p.Revision, int64(806191967); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// "checked, unchanged" gives the last version back, not a parsed one
+	last := p
+	last.StableVersion = "not parsed"
+	last.T = last.T.Add(-time.Hour)
+	for _, e := range []bool{true, false} {
+		etags = e
+		p, err := RefreshPage(ctx, last, s.URL, DefaultRules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.StableVersion, "not parsed"; have != want {
+			t.Errorf("etags %t: have %q, want %q", e, have, want)
+		}
+		if !p.T.After(last.T) {
+			t.Errorf("etags %t: T not updated", e)
+		}
+	}
+
+	// parsed with other rules, or by an older version
+	for _, parser := range []string{"", "0-12345678", DefaultRules[1:].Version()} {
+		etags = true
+		old := last
+		old.Parser = parser
+		p, err := RefreshPage(ctx, old, s.URL, DefaultRules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.StableVersion, "2.14.2 / 22 September 2017"; have != want {
+			t.Errorf("parser %q: have %q, want %q", parser, have, want)
+		}
+		if have, want := p.Parser, DefaultRules.Version(); have != want {
+			t.Errorf("parser %q: have %q, want %q", parser, have, want)
+		}
+	}
+
+	// new revision
+	etags = false
+	last.Revision = 12
+	p, err = RefreshPage(ctx, last, s.URL, DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.StableVersion, "2.14.2 / 22 September 2017"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestGetPageSelector(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>
+<table class="infobox"><caption>Foo Server</caption>
+<tr><th scope="row">Stable release</th><td>2.0</td></tr>
+</table>
+<table class="infobox"><caption>Foo Client</caption>
+<tr><th scope="row">Stable release</th><td>1.3</td></tr>
+</table>
+</body></html>`))
+	}))
+	defer s.Close()
+
+	for page, want := range map[string]string{
+		"Foo":            "2.0",
+		"Foo|Foo_Server": "2.0",
+		"Foo|Foo_Client": "1.3",
+	} {
+		p, err := GetPage(ctx, page, s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := p.StableVersion; have != want {
+			t.Errorf("%q: have %q, want %q", page, have, want)
+		}
+	}
+
+	_, err := GetPage(ctx, "Foo|foo_client", s.URL)
+	if have, want := err, (ErrRedirect{Page: "Foo|foo_client", To: "Foo|Foo_Client"}); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = GetPage(ctx, "Foo|Foo_Mobile", s.URL)
+	if have, want := err.Error(), `"Foo|Foo_Mobile": no such infobox (have: Foo Server, Foo Client)`; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestGetPageCanceled(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// never answers
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := GetPage(ctx, "Git", s.URL); err == nil {
+		t.Fatal("expected an error")
+	}
+	if have, want := ctx.Err(), context.DeadlineExceeded; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestParseInfoboxMetadata(t *testing.T) {
+	r, err := os.Open("./data/postgresql.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	have := ParseInfobox(r)
+	want := Infobox{
+		Caption:    "PostgreSQL",
+		Stable:     "10.0 / 5 October 2017",
+		Homepage:   "postgresql.org",
+		Developer:  "PostgreSQL Global Development Group",
+		License:    "PostgreSQL License (free and open-source, permissive)",
+		WrittenIn:  "C (pgAdmin: wxWidgets)",
+		OS:         "Most Unix-like operating systems and Windows",
+		Repository: "git.postgresql.org/gitweb/?p=postgresql.git",
+	}
+	if have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
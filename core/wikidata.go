@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// WikidataAPI is the Wikidata API endpoint
+	WikidataAPI = "https://www.wikidata.org/w/api.php"
+
+	propVersion     = "P348"  // software version identifier
+	propPublication = "P577"  // publication date
+	propVersionType = "P548"  // version type
+	propWebsite     = "P856"  // official website
+	propRepository  = "P1324" // source code repository
+)
+
+// version types (P548) which are not stable releases
+var previewTypes = map[string]bool{
+	"Q2122918":  true, // alpha version
+	"Q3295609":  true, // beta version
+	"Q1072356":  true, // release candidate
+	"Q51930650": true, // pre-release
+	"Q21727724": true, // unstable version
+}
+
+type wdEntities struct {
+	Entities map[string]wdEntity `json:"entities"`
+}
+
+type wdEntity struct {
+	ID        string  `json:"id"`
+	Missing   *string `json:"missing"`
+	Sitelinks map[string]struct {
+		Title string `json:"title"`
+	} `json:"sitelinks"`
+	Claims map[string][]wdStatement `json:"claims"`
+}
+
+type wdStatement struct {
+	Mainsnak   wdSnak              `json:"mainsnak"`
+	Qualifiers map[string][]wdSnak `json:"qualifiers"`
+	Rank       string              `json:"rank"` // preferred, normal, deprecated
+}
+
+type wdSnak struct {
+	Datavalue struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"datavalue"`
+}
+
+// str reads a string value. Empty if it's not a string value.
+func (s wdSnak) str() string {
+	var v string
+	json.Unmarshal(s.Datavalue.Value, &v)
+	return v
+}
+
+// id reads an item value ("Q123"). Empty if it's not an item value.
+func (s wdSnak) id() string {
+	var v struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(s.Datavalue.Value, &v)
+	return v.ID
+}
+
+// date reads a time value, and formats it the way the infobox does.
+func (s wdSnak) date() string {
+	var v struct {
+		Time      string `json:"time"`
+		Precision int    `json:"precision"`
+	}
+	if err := json.Unmarshal(s.Datavalue.Value, &v); err != nil {
+		return ""
+	}
+	// "+2017-10-00T00:00:00Z". Month and day are 00 for low precision dates.
+	var y, m, d int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(v.Time, "+"), "%d-%d-%dT", &y, &m, &d); err != nil {
+		return ""
+	}
+	if m == 0 {
+		m = 1
+	}
+	if d == 0 {
+		d = 1
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	switch v.Precision {
+	case 9:
+		return t.Format("2006")
+	case 10:
+		return t.Format("January 2006")
+	default:
+		return t.Format("2 January 2006")
+	}
+}
+
+// GetWikidata reads the versions (P348) from the Wikidata item which is
+// linked to the given wikipedia page. api is the URL of the Wikidata api.php.
+func GetWikidata(ctx context.Context, page, api string) (Page, error) {
+	p := Page{
+		Page: page,
+		T:    time.Now().UTC(),
+	}
+	if _, caption := Selector(page); caption != "" {
+		// wikidata has a single item per article
+		return p, fmt.Errorf("%q: the wikidata source doesn't support infobox selectors", page)
+	}
+
+	e, err := wikidataEntity(ctx, page, api)
+	if err != nil {
+		return p, err
+	}
+	p.Wikidata = e.ID
+	lang, title := Language(page)
+	if sl, ok := e.Sitelinks[lang+"wiki"]; ok {
+		if to := strings.Replace(sl.Title, " ", "_", -1); to != title {
+			return p, ErrRedirect{Page: page, To: LangPage(lang, to)}
+		}
+	}
+
+	stable, preview := wikidataVersions(e.Claims[propVersion])
+	p.StableVersion = strings.Join(stable, "\n")
+	p.PreviewVersion = strings.Join(preview, "\n")
+	p.Homepage = stripScheme(wikidataString(e.Claims[propWebsite]))
+	p.Repository = stripScheme(wikidataString(e.Claims[propRepository]))
+	if p.StableVersion == "" {
+		return p, fmt.Errorf("%q: no version found", page)
+	}
+	p.Releases = ParseReleases(p.StableVersion)
+	p.ReleaseDate = LatestDate(p.Releases)
+	p.PreviewReleases = ParseReleases(p.PreviewVersion)
+	return p, nil
+}
+
+func wikidataEntity(ctx context.Context, page, api string) (*wdEntity, error) {
+	u, err := url.Parse(api)
+	if err != nil {
+		return nil, err
+	}
+	lang, title := Language(page)
+	u.RawQuery = url.Values{
+		"action":     {"wbgetentities"},
+		"sites":      {lang + "wiki"},
+		"titles":     {strings.Replace(title, "_", " ", -1)},
+		"props":      {"claims|sitelinks"},
+		"sitefilter": {lang + "wiki"},
+		"normalize":  {"1"},
+		"format":     {"json"},
+	}.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	r, err := do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		return nil, fmt.Errorf("%q: wikidata error (status: %d)", page, r.StatusCode)
+	}
+
+	var res wdEntities
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("%q: %s", page, err)
+	}
+	for _, e := range res.Entities {
+		if e.Missing != nil || e.ID == "" {
+			return nil, ErrNotFound{Page: page}
+		}
+		return &e, nil
+	}
+	return nil, ErrNotFound{Page: page}
+}
+
+// wikidataString is the first non-deprecated string value
+func wikidataString(ss []wdStatement) string {
+	for _, s := range ss {
+		if s.Rank != "deprecated" {
+			return s.Mainsnak.str()
+		}
+	}
+	return ""
+}
+
+// wikidataVersions picks the stable and preview versions. If there are
+// "preferred" statements only those are used. Versions are formatted like
+// the infobox: "2.14.2 / 22 September 2017".
+func wikidataVersions(ss []wdStatement) ([]string, []string) {
+	best := func(preview bool) []string {
+		var preferred, normal []string
+		for _, s := range ss {
+			isPreview := false
+			for _, q := range s.Qualifiers[propVersionType] {
+				if previewTypes[q.id()] {
+					isPreview = true
+				}
+			}
+			if isPreview != preview {
+				continue
+			}
+			v := s.Mainsnak.str()
+			if v == "" {
+				continue
+			}
+			for _, q := range s.Qualifiers[propPublication] {
+				if d := q.date(); d != "" {
+					v += " / " + d
+					break
+				}
+			}
+			switch s.Rank {
+			case "preferred":
+				preferred = append(preferred, v)
+			case "normal":
+				normal = append(normal, v)
+			}
+		}
+		if len(preferred) > 0 {
+			return preferred
+		}
+		if len(normal) > 0 {
+			// without a preferred statement we take the last one listed
+			return normal[len(normal)-1:]
+		}
+		return nil
+	}
+	return best(false), best(true)
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wikidataServer serves the recorded entities from ./data/wikidata/
+func wikidataServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if have, want := q.Get("action"), "wbgetentities"; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		title := strings.Replace(q.Get("titles"), " ", "_", -1)
+		http.ServeFile(w, r, "./data/wikidata/"+title+".json")
+	}))
+}
+
+func TestGetWikidata(t *testing.T) {
+	ctx := context.Background()
+	s := wikidataServer(t)
+	defer s.Close()
+
+	type cas struct {
+		Page     string
+		Stable   string
+		Preview  string
+		Home     string
+		Repo     string
+		Wikidata string
+	}
+	for _, c := range []cas{
+		{
+			Page:     "Git",
+			Stable:   "2.14.2 / 22 September 2017",
+			Preview:  "2.15.0-rc2 / 19 October 2017",
+			Home:     "git-scm.com",
+			Repo:     "github.com/git/git",
+			Wikidata: "Q186055",
+		},
+		{
+			Page:     "Python_(programming_language)",
+			Stable:   "3.6.3 / 3 October 2017\n2.7.14 / 16 September 2017",
+			Preview:  "3.7.0a2 / October 2017",
+			Home:     "www.python.org",
+			Wikidata: "Q28865",
+		},
+		{
+			Page:     "Pine_(email_client)",
+			Stable:   "4.64",
+			Wikidata: "Q598868",
+		},
+	} {
+		p, err := GetWikidata(ctx, c.Page, s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.StableVersion, c.Stable; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.PreviewVersion, c.Preview; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Homepage, c.Home; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Repository, c.Repo; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Wikidata, c.Wikidata; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+
+	{
+		_, err := GetWikidata(ctx, "Python", s.URL)
+		if have, want := err, (ErrRedirect{Page: "Python", To: "Python_(programming_language)"}); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	{
+		_, err := GetWikidata(ctx, "Nosuch", s.URL)
+		if have, want := err, (ErrNotFound{Page: "Nosuch"}); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	{
+		_, err := GetWikidata(ctx, "Lorem", s.URL)
+		if have, want := err.Error(), `"Lorem": no version found`; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// WikiAPI is the MediaWiki API endpoint for the English wikipedia
+	WikiAPI = "https://en.wikipedia.org/w/api.php"
+
+	latestStable  = "Template:Latest stable software release/"
+	latestPreview = "Template:Latest preview software release/"
+)
+
+// apiQuery is the part of a formatversion=2 "action=query" response we need
+type apiQuery struct {
+	Query struct {
+		Pages []struct {
+			Title     string        `json:"title"`
+			Missing   bool          `json:"missing"`
+			Invalid   bool          `json:"invalid"`
+			Revisions []apiRevision `json:"revisions"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+type apiRevision struct {
+	RevID     int64     `json:"revid"`
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+	Slots     struct {
+		Main struct {
+			Content string `json:"content"`
+		} `json:"main"`
+	} `json:"slots"`
+}
+
+// GetWikitext loads the wikitext of a page via the MediaWiki API, and reads
+// the {{Infobox software}} template. api is the URL of api.php.
+func GetWikitext(ctx context.Context, page, api string) (Page, error) {
+	p := Page{
+		Page: page,
+		T:    time.Now().UTC(),
+	}
+	if lang, _ := Language(page); lang != DefaultLanguage {
+		// other wikipedias have their own infobox templates
+		return p, fmt.Errorf("%q: the wikitext source only reads English pages", page)
+	}
+	if _, caption := Selector(page); caption != "" {
+		return p, fmt.Errorf("%q: the wikitext source doesn't support infobox selectors", page)
+	}
+
+	rev, err := wikitextRevision(ctx, page, api)
+	if err != nil {
+		return p, err
+	}
+	text := rev.Slots.Main.Content
+	p.Revision, p.RevisionTime, p.Editor = rev.RevID, rev.Timestamp.UTC(), rev.User
+	if to := redirectTarget(text); to != "" {
+		return p, ErrRedirect{Page: page, To: to}
+	}
+
+	params := InfoboxParams(text)
+	if params == nil {
+		return p, fmt.Errorf("%q: no infobox found", page)
+	}
+
+	// Big projects keep their versions in a separate template, which we have
+	// to load.
+	for _, sub := range []struct {
+		template, version, date string
+	}{
+		{latestStable, "latest release version", "latest release date"},
+		{latestPreview, "latest preview version", "latest preview date"},
+	} {
+		v := params[sub.version]
+		if !strings.Contains(v, "{{"+strings.TrimPrefix(sub.template, "Template:")) {
+			continue
+		}
+		t, err := wikitext(ctx, sub.template+subpage(v), api)
+		if err != nil {
+			return p, err
+		}
+		sp := templateParams(t, "LSR")
+		if sp == nil {
+			return p, fmt.Errorf("%q: unexpected %s", page, sub.template)
+		}
+		params[sub.version] = sp[sub.version]
+		if d, ok := sp[sub.date]; ok {
+			params[sub.date] = d
+		}
+	}
+
+	p.StableVersion = versionLine(params["latest release version"], params["latest release date"])
+	p.PreviewVersion = versionLine(params["latest preview version"], params["latest preview date"])
+	p.Homepage = stripScheme(cleanWikitext(params["website"]))
+	p.Developer = cleanWikitext(params["developer"])
+	p.License = cleanWikitext(params["license"])
+	p.WrittenIn = cleanWikitext(params["programming language"])
+	p.OS = cleanWikitext(params["operating system"])
+	p.Repository = stripScheme(cleanWikitext(params["repo"]))
+	if p.StableVersion == "" {
+		return p, fmt.Errorf("%q: no version found", page)
+	}
+	p.Releases = ParseReleases(p.StableVersion)
+	p.ReleaseDate = LatestDate(p.Releases)
+	p.PreviewReleases = ParseReleases(p.PreviewVersion)
+	return p, nil
+}
+
+// wikitext downloads the source of a single page
+func wikitext(ctx context.Context, page, api string) (string, error) {
+	rev, err := wikitextRevision(ctx, page, api)
+	return rev.Slots.Main.Content, err
+}
+
+// wikitextRevision downloads the latest revision of a single page
+func wikitextRevision(ctx context.Context, page, api string) (apiRevision, error) {
+	var rev apiRevision
+	u, err := url.Parse(api)
+	if err != nil {
+		return rev, err
+	}
+	u.RawQuery = url.Values{
+		"action":        {"query"},
+		"prop":          {"revisions"},
+		"rvprop":        {"content|ids|timestamp|user"},
+		"rvslots":       {"main"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+		"titles":        {strings.Replace(page, "_", " ", -1)},
+	}.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return rev, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	r, err := do(ctx, req)
+	if err != nil {
+		return rev, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		return rev, fmt.Errorf("%q: wikipedia API error (status: %d)", page, r.StatusCode)
+	}
+
+	var res apiQuery
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return rev, fmt.Errorf("%q: %s", page, err)
+	}
+	ps := res.Query.Pages
+	if len(ps) == 0 || ps[0].Missing || ps[0].Invalid || len(ps[0].Revisions) == 0 {
+		return rev, ErrNotFound{Page: page}
+	}
+	return ps[0].Revisions[0], nil
+}
+
+var redirectRe = regexp.MustCompile(`(?i)^\s*#REDIRECT\s*\[\[([^\]|#]+)`)
+
+// redirectTarget gives the page name of a "#REDIRECT [[Foo]]" page
+func redirectTarget(text string) string {
+	m := redirectRe.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return strings.Replace(strings.TrimSpace(m[1]), " ", "_", -1)
+}
+
+// subpage gives "Git" from "{{Latest stable software release/Git}}"
+func subpage(v string) string {
+	v = v[strings.Index(v, "/")+1:]
+	if i := strings.Index(v, "}}"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// InfoboxParams returns the parameters of the first software infobox. Keys are
+// lowercased and use spaces, not underscores. Values are the raw wikitext.
+func InfoboxParams(text string) map[string]string {
+	for _, name := range []string{
+		"Infobox software",
+		"Infobox OS",
+		"Infobox operating system",
+		"Infobox programming language",
+		"Infobox web browser",
+	} {
+		if ps := templateParams(text, name); ps != nil {
+			return ps
+		}
+	}
+	return nil
+}
+
+// templateParams finds the first {{name ...}} template and returns its named
+// parameters. Returns nil if there is no such template.
+func templateParams(text, name string) map[string]string {
+	text = commentRe.ReplaceAllString(text, "")
+	lower := strings.ToLower(text)
+	needle := "{{" + strings.ToLower(name)
+	start := -1
+	for off := 0; ; {
+		i := strings.Index(lower[off:], needle)
+		if i < 0 {
+			return nil
+		}
+		i += off
+		// "{{Infobox software" but not "{{Infobox software license"
+		rest := strings.TrimLeft(lower[i+len(needle):], " \t")
+		if rest == "" || strings.ContainsRune("|}\n", rune(rest[0])) {
+			start = i
+			break
+		}
+		off = i + len(needle)
+	}
+
+	body := text[start+2:]
+	end := matching(body)
+	if end < 0 {
+		return nil
+	}
+	body = body[:end]
+
+	params := map[string]string{}
+	for i, f := range splitParams(body) {
+		if i == 0 {
+			continue // template name
+		}
+		eq := strings.Index(f, "=")
+		if eq < 0 {
+			continue
+		}
+		k := strings.ToLower(strings.TrimSpace(f[:eq]))
+		k = strings.Replace(k, "_", " ", -1)
+		params[k] = strings.TrimSpace(f[eq+1:])
+	}
+	return params
+}
+
+// matching returns the offset of the "}}" which closes an already opened
+// template.
+func matching(s string) int {
+	depth := 1
+	for i := 0; i < len(s)-1; i++ {
+		switch s[i : i+2] {
+		case "{{":
+			depth++
+			i++
+		case "}}":
+			depth--
+			if depth == 0 {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// splitParams splits on the top-level |s, ignoring |s in nested templates and
+// links.
+func splitParams(s string) []string {
+	var (
+		res   []string
+		depth = 0
+		last  = 0
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], "}}"), strings.HasPrefix(s[i:], "]]"):
+			depth--
+			i++
+		case s[i] == '|' && depth == 0:
+			res = append(res, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(res, s[last:])
+}
+
+var (
+	commentRe  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	refRe      = regexp.MustCompile(`(?is)<ref[^>/]*/>|<ref[^>]*>.*?</ref>`)
+	brRe       = regexp.MustCompile(`(?i)<br\s*/?>`)
+	tagRe      = regexp.MustCompile(`<[^>]+>`)
+	extLinkRe  = regexp.MustCompile(`\[(?:https?:)?//\S+\s+([^\]]+)\]`)
+	bareLinkRe = regexp.MustCompile(`\[((?:https?:)?//[^\s\]]+)\]`)
+)
+
+// cleanWikitext makes a readable string from a wikitext parameter value
+func cleanWikitext(v string) string {
+	v = commentRe.ReplaceAllString(v, "")
+	v = refRe.ReplaceAllString(v, "")
+	v = expandTemplates(v)
+	v = expandLinks(v)
+	v = extLinkRe.ReplaceAllString(v, "$1")
+	v = bareLinkRe.ReplaceAllString(v, "$1")
+	v = brRe.ReplaceAllString(v, "\n")
+	v = tagRe.ReplaceAllString(v, "")
+	v = strings.Replace(v, "'''", "", -1)
+	v = strings.Replace(v, "''", "", -1)
+	v = strings.Replace(v, "&nbsp;", " ", -1)
+	return cleanSpace(v)
+}
+
+// expandTemplates replaces the templates we know, and removes the others.
+func expandTemplates(v string) string {
+	for {
+		i := strings.LastIndex(v, "{{")
+		if i < 0 {
+			return v
+		}
+		end := strings.Index(v[i:], "}}")
+		if end < 0 {
+			return v[:i]
+		}
+		v = v[:i] + expandTemplate(v[i+2:i+end]) + v[i+end+2:]
+	}
+}
+
+// expandTemplate expands a single (innermost) template
+func expandTemplate(t string) string {
+	args := strings.Split(t, "|")
+	name := strings.ToLower(strings.TrimSpace(args[0]))
+	var pos []string // positional arguments
+	for _, a := range args[1:] {
+		if !strings.Contains(a, "=") {
+			pos = append(pos, strings.TrimSpace(a))
+		}
+	}
+	switch name {
+	case "start date and age", "start date", "release date and age", "release date", "release_date", "start_date_and_age":
+		return templateDate(pos)
+	case "url", "official url":
+		if len(pos) > 1 && pos[1] != "" {
+			return pos[1]
+		}
+		if len(pos) > 0 {
+			return pos[0]
+		}
+	case "ubl", "unbulleted list", "plainlist", "plain list", "flatlist", "hlist":
+		return strings.Join(pos, "\n")
+	case "nowrap", "nobr", "small":
+		return strings.Join(pos, " ")
+	}
+	return ""
+}
+
+// templateDate formats {{Start date and age|2017|09|22}} arguments as "22
+// September 2017", like wikipedia does.
+func templateDate(args []string) string {
+	var n []int
+	for _, a := range args {
+		i, err := strconv.Atoi(a)
+		if err != nil {
+			break
+		}
+		n = append(n, i)
+	}
+	switch len(n) {
+	case 0:
+		return ""
+	case 1:
+		return strconv.Itoa(n[0])
+	case 2:
+		return time.Date(n[0], time.Month(n[1]), 1, 0, 0, 0, 0, time.UTC).Format("January 2006")
+	default:
+		return time.Date(n[0], time.Month(n[1]), n[2], 0, 0, 0, 0, time.UTC).Format("2 January 2006")
+	}
+}
+
+// expandLinks replaces [[Foo|bar]] with bar, and [[Foo]] with Foo
+func expandLinks(v string) string {
+	for {
+		i := strings.Index(v, "[[")
+		if i < 0 {
+			return v
+		}
+		end := strings.Index(v[i:], "]]")
+		if end < 0 {
+			return v
+		}
+		l := v[i+2 : i+end]
+		if p := strings.LastIndex(l, "|"); p >= 0 {
+			l = l[p+1:]
+		}
+		v = v[:i] + l + v[i+end+2:]
+	}
+}
+
+// versionLine combines version and date in the format the HTML infobox uses:
+// "2.14.2 / 22 September 2017".
+func versionLine(version, date string) string {
+	version = cleanWikitext(version)
+	date = cleanWikitext(date)
+	if version == "" {
+		return ""
+	}
+	if date == "" {
+		return version
+	}
+	return version + " / " + date
+}
+
+func stripScheme(u string) string {
+	u = strings.TrimPrefix(u, "https://")
+	u = strings.TrimPrefix(u, "http://")
+	return strings.TrimSuffix(u, "/")
+}
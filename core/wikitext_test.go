@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apiServer serves the recorded MediaWiki API responses from ./data/api/
+func apiServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title := strings.NewReplacer(" ", "_", ":", "_", "/", "_").Replace(r.URL.Query().Get("titles"))
+		http.ServeFile(w, r, "./data/api/"+title+".json")
+	}))
+}
+
+func TestGetWikitext(t *testing.T) {
+	ctx := context.Background()
+	s := apiServer(t)
+	defer s.Close()
+
+	type cas struct {
+		Page    string
+		Stable  string
+		Preview string
+		Home    string
+		License string
+		OS      string
+	}
+	for _, c := range []cas{
+		{
+			Page:    "Git",
+			Stable:  "2.14.2 / 22 September 2017",
+			Preview: "2.15.0-rc2 / 19 October 2017",
+			Home:    "git-scm.com",
+			License: "GNU GPL v2 and GNU LGPL v2.1",
+			OS:      "POSIX: Linux, Windows, macOS",
+		},
+		{
+			Page:    "PostgreSQL",
+			Stable:  "10.0 / 5 October 2017",
+			Home:    "postgresql.org",
+			License: "PostgreSQL License",
+			OS:      "Unix-like, Windows",
+		},
+	} {
+		p, err := GetWikitext(ctx, c.Page, s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.StableVersion, c.Stable; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.PreviewVersion, c.Preview; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.Homepage, c.Home; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.License, c.License; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if have, want := p.OS, c.OS; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+		if p.ReleaseDate.IsZero() {
+			t.Errorf("no release date")
+		}
+	}
+
+	{
+		_, err := GetWikitext(ctx, "Postgres", s.URL)
+		if have, want := err, (ErrRedirect{Page: "Postgres", To: "PostgreSQL"}); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	{
+		_, err := GetWikitext(ctx, "Nosuch", s.URL)
+		if have, want := err, (ErrNotFound{Page: "Nosuch"}); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	{
+		_, err := GetWikitext(ctx, "Lorem", s.URL)
+		if have, want := err.Error(), `"Lorem": no infobox found`; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func TestTemplateParams(t *testing.T) {
+	have := templateParams(`Foo {{Infobox software license|a=b}} {{Infobox software
+| name = Foo<!-- a | comment -->
+| latest_release_version = {{Start date|2017|1|2}} [[a|b]]
+| website = [http://example.com example]
+}} bar`, "Infobox software")
+	want := map[string]string{
+		"name":                   "Foo",
+		"latest release version": "{{Start date|2017|1|2}} [[a|b]]",
+		"website":                "[http://example.com example]",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestCleanWikitext(t *testing.T) {
+	for v, want := range map[string]string{
+		"2.14.2": "2.14.2",
+		"{{Start date and age|2017|09|22|df=yes}}": "22 September 2017",
+		"{{Start date|2017|09}}":                   "September 2017",
+		"[[C (programming language)|C]], [[Perl]]": "C, Perl",
+		"10.0<ref>{{cite web|url=x}}</ref>":        "10.0",
+		`10.0<ref name="a"/>`:                      "10.0",
+		"{{URL|https://git-scm.com}}":              "https://git-scm.com",
+		"{{URL|example.com|Example}}":              "Example",
+		"[http://example.com example]":             "example",
+		"a<br />b":                                 "a\nb",
+		"{{ubl|3.6.3|2.7.14}}":                     "3.6.3\n2.7.14",
+		"'''bold''' {{unknown|template}}":          "bold",
+	} {
+		if have := cleanWikitext(v); have != want {
+			t.Errorf("%q: have %q, want %q", v, have, want)
+		}
+	}
+}
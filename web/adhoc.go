@@ -14,11 +14,13 @@ import (
 
 func adhocAtomHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
 		pages := r.URL.Query()["p"]
 		sort.Strings(pages)
-		actualPages, _ := runUpdates(db, fetch, pages)
+		ch, licenses := readChannel(r), readLicenses(r)
+		actualPages, _ := runUpdates(ctx, db, fetch, pages)
 
-		vs, err := db.History(actualPages...)
+		vs, err := db.History(ctx, actualPages...)
 		if err != nil {
 			log.Printf("history: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -30,11 +32,13 @@ func adhocAtomHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle
 			asURN(strings.Join(actualPages, ",")),
 			strings.Join(core.Titles(actualPages), ", "),
 			time.Time{},
+			ch,
+			licenses,
 			vs,
 		)
 		feed.Links = []Link{
 			{
-				Href: adhocURL(base, actualPages),
+				Href: adhocURL(base, actualPages, ch, licenses),
 				Rel:  "self",
 				Type: "application/atom+xml",
 			},
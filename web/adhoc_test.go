@@ -1,8 +1,10 @@
 package web_test
 
 import (
+	"context"
 	"encoding/xml"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -12,16 +14,17 @@ import (
 )
 
 func TestAdhoc(t *testing.T) {
+	ctx := context.Background()
 	var (
 		db = core.NewMemory()
 		m  = web.Mux("", db, web.NotFetcher(), "")
 	)
 	s := httptest.NewServer(m)
 	defer s.Close()
-	db.Store(core.Page{Page: "Debian", StableVersion: "my version"})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.1.0 / July 20, 2015", T: time.Now()})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.0 / July 21, 2016", T: time.Now()})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "my version"})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.1.0 / July 20, 2015", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.0 / July 21, 2016", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017", T: time.Now()})
 
 	status, body := get(t, s, "/adhoc/atom.xml?p=Glasgow_Haskell_Compiler")
 	if have, want := status, 200; have != want {
@@ -42,3 +45,196 @@ func TestAdhoc(t *testing.T) {
 		t.Errorf("have %v, want %v", have, want)
 	}
 }
+
+func TestAdhocReleases(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	for _, v := range []string{
+		"Standard 56.0.1 / 9 October 2017\nESR 52.4.1 / 9 October 2017",
+		"Standard 56.0.2 / 26 October 2017\nESR 52.4.1 / 9 October 2017",
+	} {
+		db.Store(ctx, core.Page{
+			Page:          "Firefox",
+			StableVersion: v,
+			Releases:      core.ParseReleases(v),
+			T:             time.Now(),
+		})
+	}
+
+	status, body := get(t, s, "/adhoc/atom.xml?p=Firefox")
+	if have, want := status, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	var f web.Feed
+	if err := xml.Unmarshal([]byte(body), &f); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(f.Entries), 2; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if have, want := f.Entries[0].Content, "Standard 56.0.2 / 26 October 2017 (was 56.0.1)\nESR 52.4.1 / 9 October 2017"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestAdhocMajor(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	for _, v := range []string{
+		"Standard 56.0.2 / 26 October 2017\nESR 52.4.1 / 9 October 2017",
+		"Standard 57.0 / 14 November 2017\nESR 52.5.0 / 14 November 2017",
+	} {
+		db.Store(ctx, core.Page{
+			Page:          "Firefox",
+			StableVersion: v,
+			Releases:      core.ParseReleases(v),
+			T:             time.Now(),
+		})
+	}
+	for _, v := range []string{
+		"3.6.3 / 3 October 2017\n2.7.14 / 16 September 2017",
+		"3.7.0 / 27 June 2018\n2.7.15 / 1 May 2018",
+	} {
+		db.Store(ctx, core.Page{
+			Page:          "Python",
+			StableVersion: v,
+			Releases:      core.ParseReleases(v),
+			T:             time.Now(),
+		})
+	}
+
+	for _, v := range []string{
+		"1.0.2 / 22 January 2015",
+		"1.0.2k / 26 January 2017",
+	} {
+		db.Store(ctx, core.Page{
+			Page:          "OpenSSL",
+			StableVersion: v,
+			Releases:      core.ParseReleases(v),
+			T:             time.Now(),
+		})
+	}
+
+	for page, want := range map[string]string{
+		"Firefox": "Standard 57.0 / 14 November 2017 (was 56.0.2)\nESR 52.5.0 / 14 November 2017 (was 52.4.1)",
+		"Python":  "3.7.0 / 27 June 2018 (was 3.6.3)\n2.7.15 / 1 May 2018 (was 2.7.14)",
+		"OpenSSL": "1.0.2k / 26 January 2017 (was 1.0.2)",
+	} {
+		_, body := get(t, s, "/adhoc/atom.xml?p="+page)
+		var f web.Feed
+		if err := xml.Unmarshal([]byte(body), &f); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := f.Entries[0].Content, want; have != want {
+			t.Errorf("have %q, want %q", have, want)
+		}
+	}
+}
+
+func TestAdhocPreview(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Firefox", StableVersion: "56.0.1", PreviewVersion: "57.0beta1", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Firefox", StableVersion: "56.0.1", PreviewVersion: "57.0beta2", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Firefox", StableVersion: "56.0.2", PreviewVersion: "57.0beta2", T: time.Now()})
+
+	for ch, want := range map[string][]string{
+		"":        {"Firefox: 56.0.2", "Firefox: 56.0.1"},
+		"stable":  {"Firefox: 56.0.2", "Firefox: 56.0.1"},
+		"preview": {"Firefox preview: 57.0beta2", "Firefox preview: 57.0beta1"},
+		"all":     {"Firefox: 56.0.2", "Firefox preview: 57.0beta2", "Firefox: 56.0.1", "Firefox preview: 57.0beta1"},
+	} {
+		status, body := get(t, s, "/adhoc/atom.xml?p=Firefox&channel="+ch)
+		if have, want := status, 200; have != want {
+			t.Fatalf("have %v, want %v", have, want)
+		}
+		var f web.Feed
+		if err := xml.Unmarshal([]byte(body), &f); err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, e := range f.Entries {
+			have = append(have, e.Title)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("channel %q: have %q, want %q", ch, have, want)
+		}
+	}
+}
+
+func TestAdhocReleaseDate(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db       = core.NewMemory()
+		m        = web.Mux("", db, web.NotFetcher(), "")
+		released = time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC)
+		seen     = time.Now().UTC().Truncate(time.Second)
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Git", StableVersion: "2.14.2 / 22 September 2017", ReleaseDate: released, T: seen})
+	db.Store(ctx, core.Page{Page: "Pine", StableVersion: "4.64", T: seen})
+
+	_, body := get(t, s, "/adhoc/atom.xml?p=Git&p=Pine")
+	var f web.Feed
+	if err := xml.Unmarshal([]byte(body), &f); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(f.Entries), 2; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	for _, e := range f.Entries {
+		want := seen
+		if strings.HasPrefix(e.Title, "Git") {
+			want = released
+		}
+		if have := e.Updated; !have.Equal(want) {
+			t.Errorf("%s: have %v, want %v", e.Title, have, want)
+		}
+	}
+}
+
+func TestAdhocLicense(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Redis", StableVersion: "7.2", License: "BSD-3-Clause", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Redis", StableVersion: "7.2", License: "RSALv2 / SSPLv1", T: time.Now()})
+
+	for url, want := range map[string][]string{
+		"/adhoc/atom.xml?p=Redis":           {"Redis: 7.2"},
+		"/adhoc/atom.xml?p=Redis&license=1": {"Redis license: RSALv2 / SSPLv1", "Redis: 7.2"},
+	} {
+		_, body := get(t, s, url)
+		var f web.Feed
+		if err := xml.Unmarshal([]byte(body), &f); err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, e := range f.Entries {
+			have = append(have, e.Title)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%s: have %v, want %v", url, have, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/alicebob/verssion/core"
+)
+
+// Admin adds the /admin/ pages to a mux, behind basic auth with user "admin".
+func Admin(r *httprouter.Router, base string, db core.DB, password string) {
+	r.GET("/admin/pending", basicAuth(password, pendingHandler(base, db)))
+}
+
+func basicAuth(password string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		user, pw, ok := r.BasicAuth()
+		if !ok || user != "admin" || subtle.ConstantTimeCompare([]byte(pw), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="verssion admin"`)
+			http.Error(w, http.StatusText(401), 401)
+			return
+		}
+		h(w, r, p)
+	}
+}
+
+type pendingChange struct {
+	Current *core.Page // nil if we don't have the page
+	Pending core.Pending
+}
+
+// pendingHandler lists the changes which are not confirmed yet
+func pendingHandler(base string, db core.DB) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		ps, err := db.PendingAll(ctx)
+		if err != nil {
+			log.Printf("pending: %s", err)
+			http.Error(w, http.StatusText(500), 500)
+			return
+		}
+		var changes []pendingChange
+		for _, p := range ps {
+			cur, err := db.Last(ctx, p.Page.Page)
+			if err != nil {
+				log.Printf("last %q: %s", p.Page.Page, err)
+			}
+			changes = append(changes, pendingChange{Current: cur, Pending: p})
+		}
+		runTmpl(w, pendingTempl, map[string]interface{}{
+			"base":    base,
+			"title":   "Pending changes",
+			"changes": changes,
+		})
+	}
+}
+
+var (
+	pendingTempl = withBase(`
+{{define "page"}}
+	<h2>Pending changes</h2>
+	{{- with .changes}}
+		<table>
+		<tr>
+			<th>Page:</th>
+			<th>Current:</th>
+			<th>Pending:</th>
+			<th class="optional">Since:</th>
+			<th class="optional">Seen:</th>
+		</tr>
+		{{- range .}}
+			<tr>
+			<td><a href="{{$.base}}/p/{{path .Pending.Page.Page}}/">{{title .Pending.Page.Page}}</a></td>
+			<td>{{with .Current}}{{version .StableVersion}}{{end}}</td>
+			<td>{{version .Pending.Page.StableVersion}}</td>
+			<td class="optional">{{.Pending.Since.Format "2006-01-02 15:04 UTC"}}</td>
+			<td class="optional">{{.Pending.Seen}}x</td>
+			</tr>
+		{{- end}}
+		</table>
+	{{- else}}
+		Nothing pending.<br />
+	{{- end}}
+{{end}}
+`)
+)
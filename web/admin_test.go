@@ -0,0 +1,62 @@
+package web_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/verssion/core"
+	"github.com/alicebob/verssion/web"
+)
+
+func TestAdminPending(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewConfirming(core.NewMemory(), core.ConfirmPolicy{Fetches: 2})
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	web.Admin(m, "", db, "secret")
+	s := httptest.NewServer(m)
+	defer s.Close()
+	now := time.Now()
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "9.2", T: now})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "6.6.6", T: now.Add(time.Minute)})
+
+	status, _ := get(t, s, "/admin/pending")
+	if have, want := status, 401; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	req, err := http.NewRequest("GET", s.URL+"/admin/pending", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("admin", "secret")
+	r, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if have, want := r.StatusCode, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contains(t, string(body),
+		"<td>9.2</td>",
+		"<td>6.6.6</td>",
+		"1x",
+	)
+
+	// the feed only has the confirmed version
+	_, page := get(t, s, "/p/Debian/")
+	if strings.Contains(page, "6.6.6") {
+		t.Errorf("unconfirmed version on the page")
+	}
+}
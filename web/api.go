@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/alicebob/verssion/core"
+)
+
+const (
+	maxSearch    = 20
+	maxAPIResult = 50
+)
+
+// apiPage is a page in the JSON endpoints
+type apiPage struct {
+	Page  string `json:"page"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// suggestHandler is /api/suggest?q=postgres[&n=5]
+func suggestHandler(base string, db core.DB) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		n := apiLimit(r, maxSuggestions)
+		writePages(w, base, suggestN(ctx, db, r.FormValue("q"), n))
+	}
+}
+
+// searchHandler is /api/search?q=postg[&n=20], for the page picker
+func searchHandler(base string, db core.DB) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		ps, err := db.Search(ctx, r.FormValue("q"), apiLimit(r, maxSearch))
+		if err != nil {
+			log.Printf("search: %s", err)
+			http.Error(w, http.StatusText(500), 500)
+			return
+		}
+		writePages(w, base, ps)
+	}
+}
+
+// apiLimit reads the n argument
+func apiLimit(r *http.Request, def int) int {
+	if v, err := strconv.Atoi(r.FormValue("n")); err == nil && v > 0 && v <= maxAPIResult {
+		return v
+	}
+	return def
+}
+
+func writePages(w http.ResponseWriter, base string, pages []string) {
+	res := []apiPage{}
+	for _, p := range pages {
+		res = append(res, apiPage{
+			Page:  p,
+			Title: core.Title(p),
+			URL:   base + "/p/" + pagePath(p) + "/",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("api: %s", err)
+	}
+}
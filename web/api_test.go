@@ -0,0 +1,84 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/verssion/core"
+	"github.com/alicebob/verssion/web"
+)
+
+func TestSuggest(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("https://example.com", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Git_(software)", StableVersion: "2.14.2", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "9.2", T: time.Now()})
+
+	status, body := get(t, s, "/api/suggest?q=gti")
+	if have, want := status, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	var have []map[string]string
+	if err := json.Unmarshal([]byte(body), &have); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{
+			"page":  "Git_(software)",
+			"title": "Git",
+			"url":   "https://example.com/p/Git_%28software%29/",
+		},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, body := get(t, s, "/api/suggest?q=nothing+like+it"); body != "[]\n" {
+		t.Errorf("have %q", body)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	for _, p := range []string{"Git_(software)", "Glasgow_Haskell_Compiler", "Haskell", "Debian"} {
+		db.Store(ctx, core.Page{Page: p, StableVersion: "1.0", T: time.Now()})
+	}
+
+	for q, want := range map[string][]string{
+		"/api/search?q=haskell":     {"Haskell", "Glasgow_Haskell_Compiler"},
+		"/api/search?q=haskell&n=1": {"Haskell"},
+		"/api/search?q=g":           {"Git_(software)", "Glasgow_Haskell_Compiler"},
+		"/api/search?q=":            {},
+	} {
+		status, body := get(t, s, q)
+		if have, want := status, 200; have != want {
+			t.Fatalf("have %v, want %v", have, want)
+		}
+		var res []struct{ Page string }
+		if err := json.Unmarshal([]byte(body), &res); err != nil {
+			t.Fatal(err)
+		}
+		have := []string{}
+		for _, r := range res {
+			have = append(have, r.Page)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%s: have %q, want %q", q, have, want)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/sha1"
 	"encoding/xml"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/alicebob/verssion/core"
@@ -21,9 +23,10 @@ type Feed struct {
 }
 
 type Link struct {
-	Href string `xml:"href,attr"`
-	Rel  string `xml:"rel,attr,omitempty"`
-	Type string `xml:"type,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr,omitempty"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
 }
 
 type Author struct {
@@ -43,24 +46,98 @@ func asURN(s string) string {
 	return fmt.Sprintf("urn:sha1:%x", n)
 }
 
-func asFeed(base, id, title string, update time.Time, vs []core.Page) Feed {
+// channel selects which releases end up in a feed
+type channel string
+
+const (
+	chanStable  channel = "stable"
+	chanPreview channel = "preview"
+	chanAll     channel = "all"
+)
+
+// readChannel gets the channel from the "channel" query argument. Defaults to
+// stable.
+func readChannel(r *http.Request) channel {
+	switch c := channel(r.URL.Query().Get("channel")); c {
+	case chanPreview, chanAll:
+		return c
+	default:
+		return chanStable
+	}
+}
+
+// readLicenses is true if the "license" query argument asks for license
+// change entries.
+func readLicenses(r *http.Request) bool {
+	return r.URL.Query().Get("license") == "1"
+}
+
+func (c channel) stable() bool {
+	return c == chanStable || c == chanAll
+}
+
+func (c channel) preview() bool {
+	return c == chanPreview || c == chanAll
+}
+
+// vs should be newest first. With licenses there are also entries for
+// license changes.
+func asFeed(base, id, title string, update time.Time, ch channel, licenses bool, vs []core.Page) Feed {
 	var es []Entry
-	for _, v := range vs {
-		es = append(es, Entry{
-			ID:      asURN(v.Page + "-" + v.StableVersion),
-			Title:   core.Title(v.Page) + ": " + v.StableVersion,
-			Updated: v.T,
-			Content: v.StableVersion, // TODO: prev version?
-			Links: []Link{
-				{
-					Href: fmt.Sprintf("%s/p/%s/", base, v.Page),
-					Rel:  "alternate", // not strictly true...
-					Type: "text/html",
-				},
+	for i, v := range vs {
+		prev := previous(vs[i+1:], v.Page)
+		links := []Link{
+			{
+				Href: fmt.Sprintf("%s/p/%s/", base, pagePath(v.Page)),
+				Rel:  "alternate", // not strictly true...
+				Type: "text/html",
 			},
-		})
-		if v.T.After(update) {
-			update = v.T
+		}
+		if u := core.RevisionURL(v.Page, v.Revision); u != "" {
+			links = append(links,
+				Link{Href: u, Rel: "via", Type: "text/html", Title: "Wikipedia revision"},
+				Link{Href: diffURL(vs, i), Rel: "related", Type: "text/html", Title: "Wikipedia diff"},
+			)
+		}
+		if ch.stable() && v.StableVersion != "" && (prev == nil || prev.StableVersion != v.StableVersion) {
+			var prevRels []core.Release
+			if prev != nil {
+				prevRels = prev.Releases
+			}
+			es = append(es, Entry{
+				ID:      asURN(v.Page + "-" + v.StableVersion),
+				Title:   core.Title(v.Page) + ": " + v.StableVersion,
+				Updated: entryTime(v.ReleaseDate, v.T),
+				Content: entryContent(v.StableVersion, v.Releases, prevRels),
+				Links:   links,
+			})
+		}
+		if ch.preview() && v.PreviewVersion != "" && (prev == nil || prev.PreviewVersion != v.PreviewVersion) {
+			var prevRels []core.Release
+			if prev != nil {
+				prevRels = prev.PreviewReleases
+			}
+			es = append(es, Entry{
+				ID:      asURN(v.Page + "-preview-" + v.PreviewVersion),
+				Title:   core.Title(v.Page) + " preview: " + v.PreviewVersion,
+				Updated: entryTime(core.LatestDate(v.PreviewReleases), v.T),
+				Content: entryContent(v.PreviewVersion, v.PreviewReleases, prevRels),
+				Links:   links,
+			})
+		}
+		if licenses && prev != nil && prev.License != v.License {
+			es = append(es, Entry{
+				ID:      asURN(v.Page + "-license-" + v.License),
+				Title:   core.Title(v.Page) + " license: " + v.License,
+				Updated: v.T,
+				Content: fmt.Sprintf("License changed from %q to %q", prev.License, v.License),
+				Links:   links,
+			})
+		}
+	}
+	for _, e := range es {
+		if e.Updated.After(update) {
+			update = e.Updated
 		}
 	}
 	return Feed{
@@ -74,3 +151,75 @@ func asFeed(base, id, title string, update time.Time, vs []core.Page) Feed {
 		Entries: es,
 	}
 }
+
+// entryTime is the release date if we know it, otherwise the time we first saw
+// the version.
+func entryTime(released, seen time.Time) time.Time {
+	if !released.IsZero() {
+		return released
+	}
+	return seen
+}
+
+// previous finds the first version of page
+func previous(vs []core.Page, page string) *core.Page {
+	for _, v := range vs {
+		if v.Page == page {
+			return &v
+		}
+	}
+	return nil
+}
+
+// diffURL links to the wikipedia changes between vs[i] and the version before
+// it. vs should be newest first.
+func diffURL(vs []core.Page, i int) string {
+	v := vs[i]
+	for _, o := range vs[i+1:] {
+		if o.Page == v.Page && o.Revision != 0 && o.Revision != v.Revision {
+			return core.DiffURL(v.Page, v.Revision, o.Revision)
+		}
+	}
+	return core.DiffURL(v.Page, v.Revision, 0)
+}
+
+// entryContent lists the releases, and what they replace.
+func entryContent(version string, rels, prevRels []core.Release) string {
+	if len(rels) == 0 {
+		return version
+	}
+	var lines []string
+	for i, r := range rels {
+		l := r.String()
+		if !r.Date.IsZero() {
+			l += " / " + r.Date.Format("2 January 2006")
+		}
+		if o := sameRelease(prevRels, i, r); o != nil {
+			switch core.CompareVersions(r.Version, o.Version) {
+			case 1:
+				l += " (was " + o.Version + ")"
+			case -1:
+				l += " (down from " + o.Version + ")"
+			}
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sameRelease finds the previous release with the same label. Releases
+// without a label are matched by position.
+func sameRelease(rs []core.Release, i int, r core.Release) *core.Release {
+	if r.Label == "" {
+		if i < len(rs) && rs[i].Label == "" {
+			return &rs[i]
+		}
+		return nil
+	}
+	for _, o := range rs {
+		if o.Label == r.Label {
+			return &o
+		}
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -13,6 +15,7 @@ import (
 
 func newCuratedHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
 		r.ParseForm()
 		var (
 			etc   = r.Form.Get("etc")
@@ -23,20 +26,22 @@ func newCuratedHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle
 			pm[p] = true
 		}
 		args := map[string]interface{}{
+			"base":     base,
 			"title":    "curated list",
 			"etc":      etc,
 			"selected": pm,
+			"picked":   pages,
 		}
 		if r.Method == "POST" {
-			pages, errors := readPageArgs(db, fetch, pages, etc)
+			pages, errors := readPageArgs(ctx, db, fetch, pages, etc)
 			if len(pages) > 0 && len(errors) == 0 {
-				id, err := db.CreateCurated()
+				id, err := db.CreateCurated(ctx)
 				if err != nil {
 					log.Printf("create curated: %s", err)
 					http.Error(w, http.StatusText(500), 500)
 					return
 				}
-				if err := db.CuratedSetPages(id, pages); err != nil {
+				if err := db.CuratedSetPages(ctx, id, pages); err != nil {
 					log.Printf("curated pages: %s", err)
 				}
 
@@ -45,20 +50,19 @@ func newCuratedHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle
 				return
 			}
 			args["errors"] = errors
+			drop, cs := didYouMean(ctx, db, errors)
+			args["candidates"] = cs
+			args["etc"] = dropPages(etc, drop)
 		}
-		avail, err := db.Known()
-		if err != nil {
-			log.Printf("known: %s", err)
-		}
-		args["available"] = avail
 		runTmpl(w, newCuratedTempl, args)
 	}
 }
 
 func curatedHandler(base string, db core.DB) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ctx := r.Context()
 		id := p.ByName("id")
-		cur, err := db.LoadCurated(id)
+		cur, err := db.LoadCurated(ctx, id)
 		if err != nil {
 			log.Printf("load curated: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -69,7 +73,7 @@ func curatedHandler(base string, db core.DB) httprouter.Handle {
 			return
 		}
 
-		vs, err := db.Current(cur.Pages...)
+		vs, err := db.Current(ctx, cur.Pages...)
 		if err != nil {
 			log.Printf("current: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -78,7 +82,10 @@ func curatedHandler(base string, db core.DB) httprouter.Handle {
 
 		args := map[string]interface{}{
 			"curated":      cur,
-			"atom":         fmt.Sprintf("%s/curated/%s/atom.xml", base, id),
+			"atom":         curatedAtomURL(base, id, chanStable, false),
+			"atompreview":  curatedAtomURL(base, id, chanPreview, false),
+			"atomall":      curatedAtomURL(base, id, chanAll, false),
+			"atomlicense":  curatedAtomURL(base, id, chanStable, true),
 			"title":        cur.Title(),
 			"pageversions": vs,
 		}
@@ -97,8 +104,9 @@ func curatedHandler(base string, db core.DB) httprouter.Handle {
 
 func curatedEditHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ctx := r.Context()
 		id := p.ByName("id")
-		cur, err := db.LoadCurated(id)
+		cur, err := db.LoadCurated(ctx, id)
 		if err != nil {
 			log.Printf("load curated: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -129,17 +137,17 @@ func curatedEditHandler(base string, db core.DB, fetch Fetcher) httprouter.Handl
 			"customtitle":  cur.CustomTitle,
 		}
 		if r.Method == "POST" {
-			pages, errors := readPageArgs(db, fetch, qPages, etc)
+			pages, errors := readPageArgs(ctx, db, fetch, qPages, etc)
 			title := r.Form.Get("title")
 			args["customtitle"] = title
 			if len(errors) == 0 {
-				if err := db.CuratedSetPages(id, pages); err != nil {
+				if err := db.CuratedSetPages(ctx, id, pages); err != nil {
 					log.Printf("curated pages: %s", err)
 					http.Error(w, http.StatusText(500), 500)
 					return
 				}
 
-				if err := db.CuratedSetTitle(id, title); err != nil {
+				if err := db.CuratedSetTitle(ctx, id, title); err != nil {
 					log.Printf("curated title: %s", err)
 				}
 
@@ -154,29 +162,32 @@ func curatedEditHandler(base string, db core.DB, fetch Fetcher) httprouter.Handl
 			}
 			args["selected"] = selected
 			args["errors"] = errors
-		}
-
-		seen := map[string]struct{}{}
-		for _, p := range cur.Pages {
-			seen[p] = struct{}{}
-		}
-		var av []string
-		if avail, err := db.Known(); err == nil {
-			for _, p := range avail {
+			drop, cs := didYouMean(ctx, db, errors)
+			args["candidates"] = cs
+			args["etc"] = dropPages(etc, drop)
+
+			// pages found with the search box
+			seen := map[string]struct{}{}
+			for _, p := range cur.Pages {
+				seen[p] = struct{}{}
+			}
+			var picked []string
+			for _, p := range qPages {
 				if _, ok := seen[p]; !ok {
-					av = append(av, p)
+					picked = append(picked, p)
 				}
 			}
+			args["picked"] = picked
 		}
-		args["available"] = av
 		runTmpl(w, curatedEditTempl, args)
 	}
 }
 
 func curatedAtomHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ctx := r.Context()
 		id := p.ByName("id")
-		cur, err := db.LoadCurated(id)
+		cur, err := db.LoadCurated(ctx, id)
 		if err != nil {
 			log.Printf("load curated: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -187,16 +198,17 @@ func curatedAtomHandler(base string, db core.DB, fetch Fetcher) httprouter.Handl
 			return
 		}
 
-		actualPages, _ := runUpdates(db, fetch, cur.Pages)
+		actualPages, _ := runUpdates(ctx, db, fetch, cur.Pages)
 
-		vs, err := db.History(actualPages...)
+		vs, err := db.History(ctx, actualPages...)
 		if err != nil {
 			log.Printf("history: %s", err)
 			http.Error(w, http.StatusText(500), 500)
 			return
 		}
 
-		feed := asFeed(base, "urn:uuid:"+id, cur.Title(), cur.LastUpdated, vs)
+		ch, licenses := readChannel(r), readLicenses(r)
+		feed := asFeed(base, "urn:uuid:"+id, cur.Title(), cur.LastUpdated, ch, licenses, vs)
 		feed.Links = []Link{
 			{
 				Href: fmt.Sprintf("%s/curated/%s/", base, id),
@@ -204,14 +216,14 @@ func curatedAtomHandler(base string, db core.DB, fetch Fetcher) httprouter.Handl
 				Type: "text/html",
 			},
 			{
-				Href: fmt.Sprintf("%s/curated/%s/atom.xml", base, id),
+				Href: curatedAtomURL(base, id, ch, licenses),
 				Rel:  "self",
 				Type: "application/atom+xml",
 			},
 		}
 		writeFeed(w, feed)
 
-		if err := db.CuratedSetUsed(id); err != nil {
+		if err := db.CuratedSetUsed(ctx, id); err != nil {
 			log.Printf("curated used %q: %s", id, err)
 		}
 	}
@@ -240,6 +252,8 @@ var (
 {{define "page"}}
 	<h2>{{.curated.Title}}</h2>
 	Atom link: <a href="{{.atom}}">{{.atom}}</a><br />
+	With preview releases: <a href="{{.atomall}}">all releases</a>, <a href="{{.atompreview}}">only previews</a><br />
+	With license changes: <a href="{{.atomlicense}}">stable releases and licenses</a><br />
 	<br />
 	{{- with .pageversions}}
 		<table>
@@ -250,8 +264,8 @@ var (
 		</tr>
 		{{- range .}}
 			<tr>
-			<td><a href="{{$.base}}/p/{{.Page}}/" title="{{.Page}}">{{title .Page}}</a></td>
-			<td>{{version .StableVersion}}</td>
+			<td><a href="{{$.base}}/p/{{path .Page}}/" title="{{.Page}}">{{title .Page}}</a></td>
+			<td>{{template "releases" .}}</td>
 			<td class="optional">{{.T.Format "2006-01-02 15:04 UTC"}}</td>
 			</tr>
 		{{- end}}
@@ -286,15 +300,50 @@ var (
 )
 
 // read p and etc arguments
-func readPageArgs(db core.DB, fetch Fetcher, pages []string, etc string) ([]string, []error) {
+func readPageArgs(ctx context.Context, db core.DB, fetch Fetcher, pages []string, etc string) ([]string, []error) {
 	var errors []error
 
 	etcPages, etcErrors := toPages(etc)
 	pages = append(pages, etcPages...)
 	errors = append(errors, etcErrors...)
 
-	finalPages, upErrors := runUpdates(db, fetch, pages)
+	finalPages, upErrors := runUpdates(ctx, db, fetch, pages)
 	errors = append(errors, upErrors...)
 
 	return unique(finalPages), errors
 }
+
+// didYouMean are the pages from errors we have suggestions for, and those
+// suggestions: the candidates of disambiguation pages, and known pages which
+// look like unknown ones.
+func didYouMean(ctx context.Context, db core.DB, errors []error) (map[string]bool, []string) {
+	var (
+		pages = map[string]bool{}
+		cs    []string
+	)
+	for _, err := range errors {
+		switch e := err.(type) {
+		case core.ErrDisambiguation:
+			pages[e.Page] = true
+			cs = append(cs, e.Candidates...)
+		case core.ErrNotFound:
+			if s := suggest(ctx, db, e.Page); len(s) > 0 {
+				pages[e.Page] = true
+				cs = append(cs, s...)
+			}
+		}
+	}
+	return pages, cs
+}
+
+// dropPages removes the lines with one of the pages from the textarea
+func dropPages(etc string, drop map[string]bool) string {
+	var keep []string
+	for _, l := range strings.Split(etc, "\n") {
+		if ps, _ := toPages(l); len(ps) == 1 && drop[ps[0]] {
+			continue
+		}
+		keep = append(keep, l)
+	}
+	return strings.Join(keep, "\n")
+}
@@ -1,7 +1,9 @@
 package web_test
 
 import (
+	"context"
 	"encoding/xml"
+	"io/ioutil"
 	"net/http/httptest"
 	"net/url"
 	"path"
@@ -13,18 +15,19 @@ import (
 )
 
 func TestCurated(t *testing.T) {
+	ctx := context.Background()
 	var (
 		db = core.NewMemory()
 		m  = web.Mux("/", db, web.NotFetcher(), "")
 	)
 	s := httptest.NewServer(m)
 	defer s.Close()
-	db.Store(core.Page{
+	db.Store(ctx, core.Page{
 		Page:          "Debian",
 		StableVersion: "my version",
 		T:             time.Now(),
 	})
-	db.Store(core.Page{
+	db.Store(ctx, core.Page{
 		Page:          "Glasgow_Haskell_Compiler",
 		StableVersion: "8.2.1 / July 22, 2017",
 		T:             time.Now(),
@@ -37,8 +40,18 @@ func TestCurated(t *testing.T) {
 		}
 		contains(t, body,
 			"Create a new list",
-			"Glasgow Haskell Compiler",
-			"Glasgow_Haskell_Compiler",
+			`<input type="search" id="search"`,
+		)
+	}
+
+	{
+		status, body := get(t, s, "/api/search?q=glasgow")
+		if have, want := status, 200; have != want {
+			t.Fatalf("have %v, want %v", have, want)
+		}
+		contains(t, body,
+			`"page":"Glasgow_Haskell_Compiler"`,
+			`"title":"Glasgow Haskell Compiler"`,
 		)
 	}
 
@@ -101,3 +114,41 @@ func TestCurated(t *testing.T) {
 		}
 	}
 }
+
+func TestCuratedDisambiguation(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db    = core.NewMemory()
+		fetch = func(_ context.Context, page string, _ *core.Page) (*core.Page, error) {
+			return nil, core.ErrDisambiguation{
+				Page:       page,
+				Candidates: []string{"Chef_(software)", "Chef_(rapper)"},
+			}
+		}
+		m = web.Mux("/", db, fetch, "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	// too old, so it gets fetched again
+	db.Store(ctx, core.Page{Page: "Chef", T: time.Now().Add(-24 * time.Hour)})
+
+	r, err := s.Client().PostForm(s.URL+"/curated/", url.Values{
+		"etc": []string{"Chef"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := r.StatusCode, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	contains(t, string(body),
+		`disambiguation page, maybe: Chef_(software), Chef_(rapper)`,
+		`<input type="checkbox" name="p" value="Chef_(software)" id="pChef_(software)"/>`,
+		`<textarea name="etc" rows="4"></textarea>`,
+	)
+}
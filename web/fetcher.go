@@ -1,17 +1,23 @@
 package web
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/alicebob/verssion/core"
 )
 
-type Fetcher func(page string) (*core.Page, error)
+// fetchTimeout limits fetching a single page, redirects included
+const fetchTimeout = 30 * time.Second
+
+// Fetcher spiders a page. last is the most recent version we have, if any,
+// so unchanged pages can be detected cheaply.
+type Fetcher func(ctx context.Context, page string, last *core.Page) (*core.Page, error)
 
 // NotFetcher doesn't fetch a page. Use in tests.
 func NotFetcher() Fetcher {
-	return func(string) (*core.Page, error) {
+	return func(context.Context, string, *core.Page) (*core.Page, error) {
 		return nil, nil
 	}
 }
@@ -20,29 +26,45 @@ var _ Fetcher = NotFetcher()
 
 // WikiFetcher loads from wikipedia
 func WikiFetcher() Fetcher {
-	up := NewUpdate()
-	return func(page string) (*core.Page, error) {
-		return up.Fetch(page, 10)
-	}
+	return SourceFetcher(core.Wikipedia{})
 }
 
 var _ Fetcher = WikiFetcher()
 
+// WikitextFetcher loads the infobox source from the MediaWiki API
+func WikitextFetcher(api string) Fetcher {
+	return SourceFetcher(core.Wikitext{API: api})
+}
+
+// WikidataFetcher loads versions from Wikidata
+func WikidataFetcher(api string) Fetcher {
+	return SourceFetcher(core.Wikidata{API: api})
+}
+
+// SourceFetcher loads from any source, such as core.Sources
+func SourceFetcher(src core.Source) Fetcher {
+	up := NewUpdate(src)
+	return func(ctx context.Context, page string, last *core.Page) (*core.Page, error) {
+		return up.Fetch(ctx, page, last, 10)
+	}
+}
+
 // loadPage returns a the lastest from the DB if that's recent enough, or uses
 // the fetcher to spider the page
-func loadPage(page string, db core.DB, fetch Fetcher) (*core.Page, error) {
-	{
-		p, err := db.Last(page)
-		if err != nil {
-			return nil, err
-		}
-		// Recent enough version found in the db
-		if p != nil && p.T.After(time.Now().Add(-cacheOK)) {
-			return p, nil
-		}
+func loadPage(ctx context.Context, page string, db core.DB, fetch Fetcher) (*core.Page, error) {
+	page = core.Canonical(page)
+	last, err := db.Last(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	// Recent enough version found in the db
+	if last != nil && last.T.After(time.Now().Add(-cacheOK)) {
+		return last, nil
 	}
 	log.Printf("go fetch %q", page)
-	p, err := fetch(page)
+	fctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	p, err := fetch(fctx, page, last)
+	cancel()
 	if err != nil {
 		return nil, err
 	}
@@ -51,9 +73,13 @@ func loadPage(page string, db core.DB, fetch Fetcher) (*core.Page, error) {
 		return nil, core.ErrNotFound{Page: page}
 	}
 
-	if err := db.Store(*p); err != nil {
+	if err := db.Store(ctx, *p); err != nil {
 		return nil, err
 	}
+	// the DB might not have taken the new version yet, see core.Confirming
+	if cur, err := db.Last(ctx, p.Page); err == nil && cur != nil {
+		return cur, nil
+	}
 
 	return p, nil
 }
@@ -12,7 +12,8 @@ import (
 
 func indexHandler(base string, db core.DB) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		es, err := db.Recent(12)
+		ctx := r.Context()
+		es, err := db.Recent(ctx, 12)
 		if err != nil {
 			log.Printf("current all: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -43,7 +44,7 @@ func readCuratedCookies(r *http.Request, db core.DB) (map[string]*core.Curated,
 			continue
 		}
 		id := t[1]
-		c, err := db.LoadCurated(id)
+		c, err := db.LoadCurated(r.Context(), id)
 		if err != nil {
 			lastErr = err
 		} else {
@@ -84,8 +85,8 @@ Make a feed which combines multiple projects in a single feed:<br />
 	<table>
 	{{- range .entries}}
 		<tr>
-			<td><a href="./p/{{.Page}}/">{{title .Page}}</a></td>
-			<td>{{version .StableVersion}}</td>
+			<td><a href="./p/{{path .Page}}/">{{title .Page}}</a></td>
+			<td>{{template "releases" .}}</td>
 		</tr>
 	{{- end}}
 		<tr>
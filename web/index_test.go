@@ -1,6 +1,7 @@
 package web_test
 
 import (
+	"context"
 	"net/http/httptest"
 	"testing"
 
@@ -9,14 +10,15 @@ import (
 )
 
 func TestIndex(t *testing.T) {
+	ctx := context.Background()
 	var (
 		db = core.NewMemory()
 		m  = web.Mux("/", db, web.NotFetcher(), "")
 	)
 	s := httptest.NewServer(m)
 	defer s.Close()
-	db.Store(core.Page{Page: "Debian", StableVersion: "my version"})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017"})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "my version"})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017"})
 
 	status, body := get(t, s, "")
 	if have, want := status, 200; have != want {
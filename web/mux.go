@@ -20,6 +20,9 @@ func Mux(baseURL string, db core.DB, up Fetcher, static string) *httprouter.Rout
 	r.GET("/curated/:id/atom.xml", curatedAtomHandler(baseURL, db, up))
 	r.GET("/p/", allPagesHandler(baseURL, db))
 	r.GET("/p/:page/", pageHandler(baseURL, db, up))
+	r.GET("/p/:page/:sub/", pageHandler(baseURL, db, up))
+	r.GET("/api/suggest", suggestHandler(baseURL, db))
+	r.GET("/api/search", searchHandler(baseURL, db))
 	if static != "" {
 		r.ServeFiles("/s/*filepath", http.Dir(static))
 	}
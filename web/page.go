@@ -12,7 +12,8 @@ import (
 
 func allPagesHandler(base string, db core.DB) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		all, err := db.CurrentAll()
+		ctx := r.Context()
+		all, err := db.CurrentAll(ctx)
 		if err != nil {
 			log.Printf("current all: %s", err)
 			http.Error(w, http.StatusText(500), 500)
@@ -28,17 +29,49 @@ func allPagesHandler(base string, db core.DB) httprouter.Handle {
 
 func pageHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ctx := r.Context()
 		page := p.ByName("page")
-		cur, err := loadPage(page, db, fetch)
+		if sub := p.ByName("sub"); sub != "" {
+			// "github:owner/repo"
+			page += "/" + sub
+		}
+		cur, err := loadPage(ctx, page, db, fetch)
 		if err != nil {
 			if p, ok := err.(core.ErrNotFound); ok {
 				log.Printf("not found %q: %s", page, err)
 				w.WriteHeader(404)
 				runTmpl(w, pageNotFoundTempl, map[string]interface{}{
-					"base":      base,
-					"title":     core.Title(p.Page),
-					"wikipedia": WikiURL(p.Page),
-					"page":      p.Page,
+					"base":        base,
+					"title":       core.Title(p.Page),
+					"source":      core.PageURL(p.Page),
+					"page":        p.Page,
+					"suggestions": suggest(ctx, db, p.Page),
+				})
+				return
+			}
+			if e, ok := err.(core.ErrDisambiguation); ok {
+				w.WriteHeader(404)
+				runTmpl(w, disambiguationTempl, map[string]interface{}{
+					"base":       base,
+					"title":      core.Title(e.Page),
+					"source":     core.PageURL(e.Page),
+					"candidates": e.Candidates,
+				})
+				return
+			}
+			if e, ok := err.(core.ErrNoInfobox); ok {
+				// link to the infoboxes which do exist
+				var others []string
+				article, _ := core.Selector(e.Page)
+				for _, c := range e.Captions {
+					others = append(others, core.Canonical(core.WithSelector(article, c)))
+				}
+				w.WriteHeader(404)
+				runTmpl(w, infoboxNotFoundTempl, map[string]interface{}{
+					"base":   base,
+					"title":  core.Title(e.Page),
+					"source": core.PageURL(e.Page),
+					"others": others,
 				})
 				return
 			}
@@ -48,25 +81,27 @@ func pageHandler(base string, db core.DB, fetch Fetcher) httprouter.Handle {
 		}
 
 		if page != cur.Page {
-			w.Header().Set("Location", fmt.Sprintf("%s/p/%s/", base, cur.Page))
+			w.Header().Set("Location", fmt.Sprintf("%s/p/%s/", base, pagePath(cur.Page)))
 			w.WriteHeader(302)
 			return
 		}
 
-		vs, err := db.History(cur.Page)
+		vs, err := db.History(ctx, cur.Page)
 		if err != nil {
 			log.Printf("history: %s", err)
 			http.Error(w, http.StatusText(500), 500)
 			return
 		}
 		runTmpl(w, pageTempl, map[string]interface{}{
-			"base":      base,
-			"title":     core.Title(cur.Page),
-			"atom":      adhocURL(base, []string{cur.Page}),
-			"wikipedia": WikiURL(cur.Page),
-			"current":   cur,
-			"page":      cur.Page,
-			"versions":  vs,
+			"base":        base,
+			"title":       core.Title(cur.Page),
+			"atom":        adhocURL(base, []string{cur.Page}, chanStable, false),
+			"atomall":     adhocURL(base, []string{cur.Page}, chanAll, false),
+			"atomlicense": adhocURL(base, []string{cur.Page}, chanStable, true),
+			"source":      core.PageURL(cur.Page),
+			"current":     cur,
+			"page":        cur.Page,
+			"versions":    vs,
 		})
 	}
 }
@@ -78,8 +113,8 @@ var (
 	<table>
 	{{- range .pages}}
 		<tr>
-			<td><a href="./{{.Page}}/" title="{{.Page}}">{{title .Page}}</a></td>
-			<td>{{version .StableVersion}}</td>
+			<td><a href="./{{path .Page}}/" title="{{.Page}}">{{title .Page}}</a></td>
+			<td>{{template "releases" .}}</td>
 		</tr>
 	{{- end}}
 	</table>
@@ -96,17 +131,59 @@ var (
 	<h2>{{title .page}}</h2>
 	<table>
 		<tr>
-			<td>Wikipedia:</td>
-			<td><a href="{{.wikipedia}}">{{.wikipedia}}</a></td>
+			<td>Source:</td>
+			<td><a href="{{.source}}">{{.source}}</a></td>
 		</tr>
+		{{- with .current.Wikidata}}
+		<tr>
+			<td>Wikidata:</td>
+			<td><a href="{{wikidata .}}">{{.}}</a></td>
+		</tr>
+		{{- end}}
 		<tr>
 			<td>Homepage:</td>
 			<td>{{with .current.Homepage}}<a href="https://{{.}}">https://{{.}}</a>{{- end}}</td>
 		</tr>
 		<tr>
 			<td>Current stable version:</td>
-			<td>{{with .current.StableVersion}}{{version .}}{{- end}}</td>
+			<td>{{template "releases" .current}}</td>
+		</tr>
+		{{- with .current.PreviewVersion}}
+		<tr>
+			<td>Current preview version:</td>
+			<td>{{version .}}</td>
+		</tr>
+		{{- end}}
+		{{- with .current.Developer}}
+		<tr>
+			<td>Developer:</td>
+			<td>{{version .}}</td>
+		</tr>
+		{{- end}}
+		{{- with .current.License}}
+		<tr>
+			<td>License:</td>
+			<td>{{version .}}</td>
+		</tr>
+		{{- end}}
+		{{- with .current.WrittenIn}}
+		<tr>
+			<td>Written in:</td>
+			<td>{{.}}</td>
 		</tr>
+		{{- end}}
+		{{- with .current.OS}}
+		<tr>
+			<td>Operating system:</td>
+			<td>{{.}}</td>
+		</tr>
+		{{- end}}
+		{{- with .current.Repository}}
+		<tr>
+			<td>Repository:</td>
+			<td><a href="https://{{.}}">https://{{.}}</a></td>
+		</tr>
+		{{- end}}
 	</table>
     <br />
     <br />
@@ -114,22 +191,38 @@ var (
 	<table class="history">
 	<tr>
 		<th class="optional">Spider timestamp:</th>
+		<th class="optional">Released:</th>
 		<th class="optional">Version:</th>
+		<th class="optional">Preview:</th>
+		<th class="optional">License:</th>
+		<th class="optional">Revision:</th>
 	</tr>
-	{{- range .versions}}
+	{{- range $i, $v := .versions}}
 		<tr>
 			<td class="optional">{{.T.Format "2006-01-02 15:04 UTC"}}</td>
+			<td class="optional">{{if not .ReleaseDate.IsZero}}{{.ReleaseDate.Format "2006-01-02"}}{{end}}</td>
 			<td>{{version .StableVersion}}</td>
+			<td>{{version .PreviewVersion}}</td>
+			<td class="optional">{{.License}}</td>
+			<td class="optional">
+				{{- with revision .Page .Revision}}<a href="{{.}}">{{$v.Revision}}</a> (<a href="{{diff $.versions $i}}">diff</a>){{end}}
+				{{- with .Editor}} by {{.}}{{end -}}
+			</td>
 		</tr>
 	{{- end}}
 	</table>
 	<br />
-	RSS link: <a href="{{.atom}}">Atom feed</a><br />
+	RSS link: <a href="{{.atom}}">Atom feed</a>, or <a href="{{.atomall}}">including preview releases</a>, or <a href="{{.atomlicense}}">including license changes</a><br />
 	<br />
 	<small>
-		Version numbers are retrieved from Wikipedia, and are licensed under Creative Commons.<br />
-		If the current stable version is out of date, please edit <a href="{{.wikipedia}}">Wikipedia</a>.<br />
+		Version numbers are retrieved from <a href="{{.source}}">{{.source}}</a>.<br />
+		If the current stable version is out of date, please edit it there.<br />
 		Latest spider check: {{if not .current.T.IsZero}}{{.current.T.Format "2006-01-02 15:04 UTC"}}{{- end}}<br />
+		{{- with revision .current.Page .current.Revision}}
+		Text from Wikipedia, available under <a href="https://creativecommons.org/licenses/by-sa/4.0/">CC BY-SA</a>; <a href="{{.}}">revision {{$.current.Revision}}</a>
+		{{- with $.current.Editor}} by {{.}}{{end}}
+		{{- with $.current.RevisionTime}}{{if not .IsZero}}, {{.Format "2006-01-02 15:04 UTC"}}{{end}}{{end}}.<br />
+		{{- end}}
 	</small>
 {{- end}}
 `)
@@ -137,7 +230,42 @@ var (
 	pageNotFoundTempl = withBase(`
 {{define "page"}}
 	Page not found: {{.page}}<br />
-	Maybe you can create it on <a href="{{.wikipedia}}">Wikipedia</a><br />
+	{{- with .suggestions}}
+	Did you mean:<br />
+	{{- range .}}
+	<a href="{{$.base}}/p/{{path .}}/">{{title .}}</a><br />
+	{{- end}}
+	<br />
+	Or maybe you can create it
+	{{- else}}
+	Maybe you can create it
+	{{- end}} on <a href="{{.source}}">{{.source}}</a><br />
+    <br />
+{{- end}}
+`)
+
+	disambiguationTempl = withBase(`
+{{define "page"}}
+	<a href="{{.source}}">{{.source}}</a> is a disambiguation page.<br />
+	{{- with .candidates}}
+	Maybe you meant one of these:<br />
+	{{- range .}}
+	<a href="{{$.base}}/p/{{path .}}/">{{title .}}</a><br />
+	{{- end}}
+	{{- end}}
+    <br />
+{{- end}}
+`)
+
+	infoboxNotFoundTempl = withBase(`
+{{define "page"}}
+	No infobox with that caption on <a href="{{.source}}">{{.source}}</a><br />
+	{{- with .others}}
+	Infoboxes on that page:<br />
+	{{- range .}}
+	<a href="{{$.base}}/p/{{path .}}/">{{title .}}</a><br />
+	{{- end}}
+	{{- end}}
     <br />
 {{- end}}
 `)
@@ -1,6 +1,7 @@
 package web_test
 
 import (
+	"context"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -11,14 +12,15 @@ import (
 )
 
 func TestPages(t *testing.T) {
+	ctx := context.Background()
 	var (
 		db = core.NewMemory()
 		m  = web.Mux("", db, web.NotFetcher(), "")
 	)
 	s := httptest.NewServer(m)
 	defer s.Close()
-	db.Store(core.Page{Page: "Debian", StableVersion: "my version"})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017"})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "my version"})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017"})
 
 	status, body := get(t, s, "/p/")
 	if have, want := status, 200; have != want {
@@ -38,15 +40,16 @@ func TestPages(t *testing.T) {
 }
 
 func TestPage(t *testing.T) {
+	ctx := context.Background()
 	var (
 		db = core.NewMemory()
 		m  = web.Mux("", db, web.NotFetcher(), "")
 	)
 	s := httptest.NewServer(m)
 	defer s.Close()
-	db.Store(core.Page{Page: "Debian", StableVersion: "my version"})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.0", T: time.Now()})
-	db.Store(core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017", Homepage: "https://haskell.org/ghc", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "my version"})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.0", T: time.Now()})
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1 / July 22, 2017", Homepage: "https://haskell.org/ghc", License: "BSD-like", T: time.Now()})
 
 	{
 		status, _ := get(t, s, "/p/Glasgow_Haskell_Compiler")
@@ -76,4 +79,152 @@ func TestPage(t *testing.T) {
 	if in, want := body, "8.2.0"; !strings.Contains(in, want) {
 		t.Fatalf("no %q found in %q", want, in)
 	}
+	if in, want := body, "BSD-like"; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+}
+
+func TestPageNamespace(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "github:golang/go", StableVersion: "go1.9.2 / 25 October 2017", T: time.Now()})
+
+	status, body := get(t, s, "/p/github:golang/go/")
+	if have, want := status, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if in, want := body, "https://github.com/golang/go"; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+	if in, want := body, "go1.9.2"; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+}
+
+func TestPageCanonical(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "PostgreSQL", StableVersion: "10.0", T: time.Now()})
+
+	status, _ := get(t, s, "/p/postgreSQL/")
+	if have, want := status, 302; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+}
+
+func TestPageSelector(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Foo|Foo_Client", StableVersion: "1.3", T: time.Now()})
+
+	status, body := get(t, s, "/p/Foo%7CFoo_Client/")
+	if have, want := status, 200; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if in, want := body, "Foo: Foo Client"; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+	if in, want := body, "https://en.wikipedia.org/wiki/Foo"; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+}
+
+func TestPageDisambiguation(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db    = core.NewMemory()
+		fetch = func(_ context.Context, page string, _ *core.Page) (*core.Page, error) {
+			return nil, core.ErrDisambiguation{
+				Page:       page,
+				Candidates: []string{"Chef_(software)"},
+			}
+		}
+		m = web.Mux("", db, fetch, "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	// too old, so it gets fetched again
+	db.Store(ctx, core.Page{Page: "Chef", T: time.Now().Add(-24 * time.Hour)})
+
+	status, body := get(t, s, "/p/Chef/")
+	if have, want := status, 404; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if in, want := body, `<a href="/p/Chef_%28software%29/">Chef</a>`; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+}
+
+func TestPageSuggestions(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Glasgow_Haskell_Compiler", StableVersion: "8.2.1", T: time.Now()})
+
+	status, body := get(t, s, "/p/Glasgow_Haskel_Compiler/")
+	if have, want := status, 404; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if in, want := body, `<a href="/p/Glasgow_Haskell_Compiler/">Glasgow Haskell Compiler</a>`; !strings.Contains(in, want) {
+		t.Fatalf("no %q found in %q", want, in)
+	}
+}
+
+func TestPageRevision(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{
+		Page:          "Git",
+		StableVersion: "2.14.2",
+		T:             time.Now().Add(-time.Hour),
+		Revision:      805000000,
+	})
+	db.Store(ctx, core.Page{
+		Page:          "Git",
+		StableVersion: "2.14.3",
+		T:             time.Now(),
+		Revision:      806191967,
+		RevisionTime:  time.Date(2017, 10, 22, 19, 4, 0, 0, time.UTC),
+		Editor:        "Example Editor",
+	})
+
+	_, body := get(t, s, "/p/Git/")
+	contains(t, body,
+		`<a href="https://en.wikipedia.org/w/index.php?oldid=806191967&amp;title=Git">806191967</a>`,
+		`<a href="https://en.wikipedia.org/w/index.php?diff=806191967&amp;oldid=805000000&amp;title=Git">diff</a>`,
+		`<a href="https://en.wikipedia.org/w/index.php?diff=prev&amp;oldid=805000000&amp;title=Git">diff</a>`,
+		"CC BY-SA",
+		"revision 806191967</a> by Example Editor, 2017-10-22 19:04 UTC.",
+	)
+
+	_, body = get(t, s, "/adhoc/atom.xml?p=Git")
+	contains(t, body,
+		`<link href="https://en.wikipedia.org/w/index.php?oldid=806191967&amp;title=Git" rel="via" type="text/html" title="Wikipedia revision"></link>`,
+		`<link href="https://en.wikipedia.org/w/index.php?diff=806191967&amp;oldid=805000000&amp;title=Git" rel="related" type="text/html" title="Wikipedia diff"></link>`,
+		`<link href="https://en.wikipedia.org/w/index.php?diff=prev&amp;oldid=805000000&amp;title=Git" rel="related" type="text/html" title="Wikipedia diff"></link>`,
+	)
 }
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"regexp"
@@ -10,9 +11,12 @@ import (
 	"github.com/alicebob/verssion/core"
 )
 
-var matchpage = regexp.MustCompile(`^(?:(?i:https?://en.wikipedia.org)/wiki/)?(\S+)$`)
+// wikipedia URL (desktop or mobile, /wiki/ or ?title=), "wiki/Page", or a
+// plain page name. Case doesn't matter in the URL part.
+var matchpage = regexp.MustCompile(`^(?i:(?:https?://([a-z]+)(?:\.m)?\.wikipedia\.org)?(/?wiki/|/w/index\.php\?(?:\S*?&)?title=))?(\S+)$`)
 
-// from textarea to pages
+// from textarea to pages. Pages are canonical. Plain page names can have an
+// infobox selector: "Foo|Foo_Server".
 func toPages(q string) ([]string, []error) {
 	var (
 		ps     []string
@@ -23,11 +27,30 @@ func toPages(q string) ([]string, []error) {
 		if len(l) == 0 {
 			continue
 		}
-		if m := matchpage.FindStringSubmatch(l); m != nil {
-			ps = append(ps, m[1])
-		} else {
+		m := matchpage.FindStringSubmatch(l)
+		if m == nil || strings.Contains(m[3], "://") {
 			errors = append(errors, fmt.Errorf("invalid page: %q", l))
+			continue
+		}
+		lang, page := strings.ToLower(m[1]), m[3]
+		if strings.HasPrefix(strings.ToLower(m[2]), "/w/") {
+			// other query arguments
+			if i := strings.Index(page, "&"); i >= 0 {
+				page = page[:i]
+			}
+		}
+		if m[2] != "" {
+			// wikipedia URLs have no infobox selector
+			page, _ = core.Selector(page)
+		}
+		if lang != "" {
+			if known, _ := core.Language(lang + ":"); known != lang {
+				errors = append(errors, fmt.Errorf("unsupported language: %q", l))
+				continue
+			}
+			page = core.LangPage(lang, page)
 		}
+		ps = append(ps, core.Canonical(page))
 	}
 	return ps, errors
 }
@@ -45,14 +68,14 @@ func unique(ps []string) []string {
 	return res
 }
 
-func runUpdates(db core.DB, fetch Fetcher, pages []string) ([]string, []error) {
+func runUpdates(ctx context.Context, db core.DB, fetch Fetcher, pages []string) ([]string, []error) {
 	var (
 		ret    []string
 		errors []error
 	)
 
 	for _, p := range pages {
-		if n, err := loadPage(p, db, fetch); err != nil {
+		if n, err := loadPage(ctx, p, db, fetch); err != nil {
 			log.Printf("update %q: %s", p, err)
 			errors = append(errors, err)
 		} else {
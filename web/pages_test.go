@@ -15,12 +15,28 @@ Foo5
 
 wiki/Foo6
 Foo 7
+https://de.wikipedia.org/wiki/Foo8
+de:Foo9
+https://ja.wikipedia.org/wiki/Foo10
+https://en.m.wikipedia.org/wiki/Foo11
+https://en.wikipedia.org/w/index.php?title=Foo12&action=history
+https://en.wikipedia.org/w/index.php?oldid=123&title=Foo13
+https://en.wikipedia.org/wiki/Foo_14#History
+foo%2015
+Foo16|foo_client
+HTTPS://EN.M.WIKIPEDIA.ORG/W/INDEX.PHP?TITLE=Foo17&ACTION=edit
+https://en.wikipedia.org/Foo18
+ftp://example.com/Foo19
 `)
 	wantOK := []string{
-		"Foo1", "Foo2", "http://En.wiKIPedia.oRg/wiKI/Foo3", "Foo4", "Foo5", "wiki/Foo6",
+		"Foo1", "Foo2", "Foo3", "Foo4", "Foo5", "Foo6", "de:Foo8", "de:Foo9",
+		"Foo11", "Foo12", "Foo13", "Foo_14", "Foo_15", "Foo16|foo_client", "Foo17",
 	}
 	wantErr := []string{
 		`invalid page: "Foo 7"`,
+		`unsupported language: "https://ja.wikipedia.org/wiki/Foo10"`,
+		`invalid page: "https://en.wikipedia.org/Foo18"`,
+		`invalid page: "ftp://example.com/Foo19"`,
 	}
 	if !reflect.DeepEqual(haveOK, wantOK) {
 		t.Errorf("have %v, want %v", haveOK, wantOK)
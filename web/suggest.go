@@ -0,0 +1,24 @@
+package web
+
+import (
+	"context"
+	"log"
+
+	"github.com/alicebob/verssion/core"
+)
+
+const maxSuggestions = 5
+
+// suggest gives known pages which look like page
+func suggest(ctx context.Context, db core.DB, page string) []string {
+	return suggestN(ctx, db, page, maxSuggestions)
+}
+
+func suggestN(ctx context.Context, db core.DB, page string, n int) []string {
+	known, err := db.Known(ctx)
+	if err != nil {
+		log.Printf("known: %s", err)
+		return nil
+	}
+	return core.Suggest(known, page, n)
+}
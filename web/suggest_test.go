@@ -0,0 +1,42 @@
+package web_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/verssion/core"
+	"github.com/alicebob/verssion/web"
+)
+
+func TestCuratedSuggestions(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db = core.NewMemory()
+		m  = web.Mux("/", db, web.NotFetcher(), "")
+	)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	db.Store(ctx, core.Page{Page: "Debian", StableVersion: "9.2", T: time.Now()})
+
+	r, err := s.Client().PostForm(s.URL+"/curated/", url.Values{
+		"etc": []string{"Debain"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contains(t, string(body),
+		`no such page`,
+		`Did you mean:`,
+		`<input type="checkbox" name="p" value="Debian" id="pDebian"/>`,
+		`<textarea name="etc" rows="4"></textarea>`,
+	)
+}
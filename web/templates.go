@@ -14,7 +14,11 @@ var (
 	baseTempl = template.Must(
 		template.New("base").
 			Funcs(template.FuncMap{
-				"title": core.Title,
+				"title":    core.Title,
+				"path":     pagePath,
+				"wikidata": WikidataURL,
+				"revision": core.RevisionURL,
+				"diff":     diffURL,
 				"version": func(s string) template.HTML {
 					h := template.HTMLEscapeString(s)
 					t := template.HTML(strings.Replace(h, "\n", "<br />", -1))
@@ -117,6 +121,14 @@ a:hover {
     {{- end}}
 {{end}}
 
+{{define "releases"}}
+    {{- with .Releases}}
+        {{- range .}}{{.}}{{if not .Date.IsZero}} / {{.Date.Format "2 January 2006"}}{{end}}<br />{{end}}
+    {{- else}}
+        {{- version .StableVersion}}
+    {{- end}}
+{{- end}}
+
 {{define "pageselection"}}
     {{- if .pages}}
     Selected pages:<br />
@@ -126,13 +138,74 @@ a:hover {
     <br />
     {{- end}}
 
+    {{- with .candidates}}
+    Did you mean:<br />
+    {{- range .}}
+        <input type="checkbox" name="p" value="{{.}}" id="p{{.}}"/><label for="p{{.}}" title="{{.}}"> {{title .}}</label><br />
+    {{- end}}
+    <br />
+    {{- end}}
+
     Add some pages:<br />
-    {{- range .available}}
-        <input type="checkbox" name="p" value="{{.}}" id="p{{.}}"{{if (index $.selected .)}} CHECKED{{end}}/><label for="p{{.}}" title="{{.}}"> {{title .}}</label><br />
+    <input type="search" id="search" size="40" placeholder="Search pages we know" autocomplete="off" /><br />
+    <div id="picked">
+    {{- range .picked}}
+        <input type="checkbox" name="p" value="{{.}}" id="p{{.}}" CHECKED/><label for="p{{.}}" title="{{.}}"> {{title .}}</label><br />
     {{- end}}
+    </div>
+    <div id="results"></div>
     <br />
+    <script>
+    (function() {
+        var search = document.getElementById("search"),
+            picked = document.getElementById("picked"),
+            results = document.getElementById("results"),
+            timer;
+        // keep the checked results when the results change
+        results.addEventListener("change", function(e) {
+            if (e.target.checked) {
+                var label = e.target.nextSibling, br = label.nextSibling;
+                picked.appendChild(e.target);
+                picked.appendChild(label);
+                picked.appendChild(br);
+            }
+        });
+        search.addEventListener("input", function() {
+            clearTimeout(timer);
+            timer = setTimeout(function() {
+                var q = search.value.trim();
+                if (q === "") {
+                    results.textContent = "";
+                    return;
+                }
+                fetch({{.base}} + "/api/search?q=" + encodeURIComponent(q))
+                    .then(function(r) { return r.json(); })
+                    .then(function(ps) {
+                        results.textContent = "";
+                        ps.forEach(function(p) {
+                            if (document.getElementById("p" + p.page)) {
+                                return;
+                            }
+                            var box = document.createElement("input"),
+                                label = document.createElement("label");
+                            box.type = "checkbox";
+                            box.name = "p";
+                            box.value = p.page;
+                            box.id = "p" + p.page;
+                            label.htmlFor = box.id;
+                            label.title = p.page;
+                            label.textContent = " " + p.title;
+                            results.appendChild(box);
+                            results.appendChild(label);
+                            results.appendChild(document.createElement("br"));
+                        });
+                    });
+            }, 200);
+        });
+    })();
+    </script>
 
-    Or add other en.wikipedia.org pages (either the full URL or the part after <code>/wiki/</code>). One per line.<br />
+    Or add other en.wikipedia.org pages (either the full URL or the part after <code>/wiki/</code>), de.wikipedia.org or fr.wikipedia.org pages (the full URL, or as <code>de:Page</code>), or projects as <code>github:owner/repo</code>, <code>pypi:name</code>, <code>npm:name</code>, or <code>crates:name</code>. One per line.<br />
     <textarea name="etc" rows="4">{{.etc}}</textarea><br />
 {{end}}
 `))
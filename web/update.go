@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ const (
 )
 
 type Update struct {
+	src   core.Source
 	mu    sync.Mutex
 	pages map[string]*last
 }
@@ -25,17 +27,15 @@ type last struct {
 	err       error
 }
 
-func NewUpdate() *Update {
+// NewUpdate caches and follows redirects for a source
+func NewUpdate(src core.Source) *Update {
 	return &Update{
+		src:   src,
 		pages: map[string]*last{},
 	}
 }
 
-func (u *Update) fetch(page string) (core.Page, error) {
-	return core.GetPage(page, WikiURL(page))
-}
-
-func (u *Update) cachedFetch(page string) (core.Page, error) {
+func (u *Update) cachedFetch(ctx context.Context, page string, prev *core.Page) (core.Page, error) {
 	u.mu.Lock()
 	l, ok := u.pages[page]
 	if !ok {
@@ -50,7 +50,16 @@ func (u *Update) cachedFetch(page string) (core.Page, error) {
 	if !l.cacheTill.IsZero() && now.Before(l.cacheTill) {
 		return l.page, l.err
 	}
-	l.page, l.err = u.fetch(page)
+	if prev != nil && prev.Page == page {
+		l.page, l.err = core.Refresh(ctx, u.src, *prev)
+	} else {
+		l.page, l.err = u.src.Fetch(ctx, page)
+	}
+	if ctx.Err() != nil {
+		// our caller gave up, which says nothing about the page
+		l.cacheTill = time.Time{}
+		return l.page, l.err
+	}
 	c := cacheOK
 	if l.err != nil {
 		c = cacheErr
@@ -60,22 +69,26 @@ func (u *Update) cachedFetch(page string) (core.Page, error) {
 }
 
 // Fetch the most recent version (or a cache).
-// Follows redirects.
-func (u *Update) Fetch(page string, redir int) (*core.Page, error) {
+// Follows redirects. prev is optional, and is the version we already have.
+func (u *Update) Fetch(ctx context.Context, page string, prev *core.Page, redir int) (*core.Page, error) {
 	if redir < 0 {
 		return nil, fmt.Errorf("%q: too many redirects", page)
 	}
 
-	p, err := u.cachedFetch(page)
+	p, err := u.cachedFetch(ctx, page, prev)
 	if err == nil {
 		return &p, nil
 	}
 	if red, ok := err.(core.ErrRedirect); ok {
-		return u.Fetch(red.To, redir-1)
+		return u.Fetch(ctx, red.To, nil, redir-1)
 	}
 	return nil, err
 }
 
 func WikiURL(page string) string {
-	return "https://en.wikipedia.org/wiki/" + page
+	return core.WikiURL(page)
+}
+
+func WikidataURL(id string) string {
+	return "https://www.wikidata.org/wiki/" + id
 }
@@ -0,0 +1,51 @@
+package web_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/verssion/core"
+	"github.com/alicebob/verssion/web"
+)
+
+type countSource struct {
+	fetches int
+}
+
+func (c *countSource) Fetch(ctx context.Context, page string) (core.Page, error) {
+	c.fetches++
+	if err := ctx.Err(); err != nil {
+		return core.Page{Page: page}, err
+	}
+	return core.Page{Page: page, StableVersion: "1.0"}, nil
+}
+
+func TestUpdateCanceled(t *testing.T) {
+	var (
+		src = &countSource{}
+		up  = web.NewUpdate(src)
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := up.Fetch(ctx, "Git", nil, 1); err != context.Canceled {
+		t.Fatalf("have %v, want %v", err, context.Canceled)
+	}
+
+	// the canceled fetch isn't cached
+	p, err := up.Fetch(context.Background(), "Git", nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.StableVersion, "1.0"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	// but a successful one is
+	if _, err := up.Fetch(context.Background(), "Git", nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := src.fetches, 2; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
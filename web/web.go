@@ -1,17 +1,48 @@
 package web
 
 import (
+	"fmt"
 	"net/url"
+	"strings"
 )
 
-func adhocURL(base string, pages []string) string {
+// pagePath escapes a page for the /p/ URLs. The slash in "github:owner/repo"
+// stays, but the "|" of an infobox selector is escaped.
+func pagePath(page string) string {
+	return strings.Replace(url.PathEscape(page), "%2F", "/", -1)
+}
+
+func adhocURL(base string, pages []string, ch channel, licenses bool) string {
 	u, err := url.Parse(base)
 	if err != nil {
 		panic(err)
 	}
 	u.Path += "/adhoc/atom.xml"
-	u.RawQuery = url.Values{
+	q := url.Values{
 		"p": pages,
-	}.Encode()
+	}
+	if ch != chanStable {
+		q.Set("channel", string(ch))
+	}
+	if licenses {
+		q.Set("license", "1")
+	}
+	u.RawQuery = q.Encode()
 	return u.String()
 }
+
+// curatedAtomURL is the feed URL of a curated list
+func curatedAtomURL(base, id string, ch channel, licenses bool) string {
+	u := fmt.Sprintf("%s/curated/%s/atom.xml", base, id)
+	q := url.Values{}
+	if ch != chanStable {
+		q.Set("channel", string(ch))
+	}
+	if licenses {
+		q.Set("license", "1")
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}